@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"auth-service/database"
+	"auth-service/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultDeletionGraceDays = 14
+
+// deletionGracePeriod returns how long a deletion request stays cancellable.
+// Configurable via ACCOUNT_DELETION_GRACE_DAYS (0 = purge on the next worker run).
+func deletionGracePeriod() time.Duration {
+	days := defaultDeletionGraceDays
+	if v := os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// RequestAccountDeletion handles POST /account/delete.
+// The account is only flagged here: the actual purge across Postgres, Redis
+// and Neo4j is done by worker.StartDeletionWorker once the grace period expires.
+func RequestAccountDeletion(c *fiber.Ctx) error {
+	userID, err := getUserIDFromSession(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.DeletionScheduledAt == nil {
+		now := time.Now().UTC()
+		scheduled := now.Add(deletionGracePeriod())
+		user.DeletionRequestedAt = &now
+		user.DeletionScheduledAt = &scheduled
+		if err := database.DB.Save(&user).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to schedule account deletion"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message":               "Account deletion scheduled",
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	})
+}
+
+// CancelAccountDeletion handles POST /account/delete/cancel — only possible
+// while the grace period is still running.
+func CancelAccountDeletion(c *fiber.Ctx) error {
+	userID, err := getUserIDFromSession(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No account deletion pending"})
+	}
+
+	if err := database.DB.Model(&user).Updates(map[string]any{
+		"deletion_requested_at": nil,
+		"deletion_scheduled_at": nil,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cancel account deletion"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Account deletion cancelled"})
+}
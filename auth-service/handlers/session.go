@@ -22,6 +22,8 @@ type SessionMeResponse struct {
 	AvatarURL    *string `json:"avatar_url,omitempty"`
 	AuthProvider string  `json:"auth_provider"`
 	Tier         string  `json:"tier"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
 const sessionCookieName = "session"
@@ -90,6 +92,8 @@ func Me(c *fiber.Ctx) error {
 		AvatarURL:    user.AvatarURL,
 		AuthProvider: user.AuthProvider,
 		Tier:         user.Tier,
+
+		DeletionScheduledAt: user.DeletionScheduledAt,
 	})
 }
 
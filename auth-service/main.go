@@ -7,6 +7,7 @@ import (
 	"auth-service/database"
 	"auth-service/handlers"
 	"auth-service/models"
+	"auth-service/worker"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -52,6 +53,13 @@ func main() {
 	app.Get("/me", handlers.Me)
 	app.Post("/logout-web", handlers.LogoutWeb)
 
+	// Routes — Account lifecycle
+	app.Post("/account/delete", handlers.RequestAccountDeletion)
+	app.Post("/account/delete/cancel", handlers.CancelAccountDeletion)
+
+	// Background purge of accounts whose deletion grace period expired
+	go worker.StartDeletionWorker()
+
 	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -1,14 +1,20 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	Email        string  `gorm:"uniqueIndex;not null" json:"email"`
-	Password     *string `gorm:"default:null"        json:"password,omitempty"`
-	AuthProvider string  `gorm:"default:'local'"     json:"auth_provider"`
-	LinkedInID   *string `gorm:"default:null"        json:"linkedin_id,omitempty"`
-	AvatarURL    *string `gorm:"default:null"        json:"avatar_url,omitempty"`
-	FullName     *string `gorm:"default:null"        json:"full_name,omitempty"`
-	Tier         string  `gorm:"default:'free'"      json:"tier"`
+	Email               string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password            *string    `gorm:"default:null"        json:"password,omitempty"`
+	AuthProvider        string     `gorm:"default:'local'"     json:"auth_provider"`
+	LinkedInID          *string    `gorm:"default:null"        json:"linkedin_id,omitempty"`
+	AvatarURL           *string    `gorm:"default:null"        json:"avatar_url,omitempty"`
+	FullName            *string    `gorm:"default:null"        json:"full_name,omitempty"`
+	Tier                string     `gorm:"default:'free'"      json:"tier"`
+	DeletionRequestedAt *time.Time `gorm:"default:null"        json:"deletion_requested_at,omitempty"`
+	DeletionScheduledAt *time.Time `gorm:"default:null;index"  json:"deletion_scheduled_at,omitempty"`
 }
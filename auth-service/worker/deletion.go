@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"auth-service/database"
+	"auth-service/models"
+)
+
+// Redis keys shared with event-service, which owns the Neo4j side of the purge.
+const (
+	DeletionQueueKey  = "account_deletion_queue"
+	DeletedUsersKey   = "deleted_users"
+	DeletionReportKey = "deletion_report:"
+	deletionReportTTL = 90 * 24 * time.Hour
+
+	// Encrypted bring-your-own LLM key, written by dashboard-server.
+	byokKey = "byok:"
+)
+
+// DeletionJob is the message handed over to event-service on DeletionQueueKey.
+type DeletionJob struct {
+	UserID      string    `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// StartDeletionWorker periodically purges accounts whose grace period expired.
+func StartDeletionWorker() {
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		purgeExpiredAccounts()
+	}
+}
+
+func purgeExpiredAccounts() {
+	var users []models.User
+	if err := database.DB.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now().UTC()).Find(&users).Error; err != nil {
+		log.Printf("Failed to load accounts pending deletion: %v\n", err)
+		return
+	}
+
+	for _, user := range users {
+		if err := purgeAccount(user); err != nil {
+			log.Printf("Failed to purge account %d: %v\n", user.ID, err)
+		}
+	}
+}
+
+// purgeAccount removes the user's Postgres row and Redis credentials, then
+// queues the graph cleanup for event-service. The report hash collects what
+// each step verified as left behind (everything should be 0).
+func purgeAccount(user models.User) error {
+	ctx := database.Ctx
+	uid := fmt.Sprint(user.ID)
+	reportKey := DeletionReportKey + uid
+
+	// Flag the user first so event-service stops writing their events to the graph.
+	if err := database.RedisClient.SAdd(ctx, DeletedUsersKey, uid).Err(); err != nil {
+		return fmt.Errorf("failed to flag deleted user: %w", err)
+	}
+
+	if err := database.RedisClient.Del(ctx, "secret:"+uid).Err(); err != nil {
+		return fmt.Errorf("failed to delete signing secret: %w", err)
+	}
+	if err := database.RedisClient.Del(ctx, byokKey+uid).Err(); err != nil {
+		return fmt.Errorf("failed to delete stored API key: %w", err)
+	}
+	if _, err := deleteSessions(ctx, uid); err != nil {
+		return fmt.Errorf("failed to delete web sessions: %w", err)
+	}
+
+	requestedAt := time.Now().UTC()
+	if user.DeletionRequestedAt != nil {
+		requestedAt = *user.DeletionRequestedAt
+	}
+	job, _ := json.Marshal(DeletionJob{UserID: uid, RequestedAt: requestedAt})
+	if err := database.RedisClient.LPush(ctx, DeletionQueueKey, job).Err(); err != nil {
+		return fmt.Errorf("failed to queue graph deletion: %w", err)
+	}
+
+	if err := database.DB.Unscoped().Delete(&models.User{}, user.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete user row: %w", err)
+	}
+
+	// Verification: re-check every store we own after the purge.
+	var pgRows int64
+	database.DB.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&pgRows)
+	sessions, _ := countSessions(ctx, uid)
+	secrets, _ := database.RedisClient.Exists(ctx, "secret:"+uid).Result()
+	apiKeys, _ := database.RedisClient.Exists(ctx, byokKey+uid).Result()
+
+	database.RedisClient.HSet(ctx, reportKey, map[string]any{
+		"user_id":                  uid,
+		"requested_at":             requestedAt.Format(time.RFC3339),
+		"auth_purged_at":           time.Now().UTC().Format(time.RFC3339),
+		"postgres_rows_remaining":  pgRows,
+		"sessions_remaining":       sessions,
+		"signing_secret_remaining": secrets,
+		"api_key_remaining":        apiKeys,
+		"status":                   "graph_pending",
+	})
+	database.RedisClient.Expire(ctx, reportKey, deletionReportTTL)
+
+	log.Printf("Account %d purged from auth stores (pg_rows=%d sessions=%d secrets=%d api_keys=%d), graph cleanup queued\n", user.ID, pgRows, sessions, secrets, apiKeys)
+	return nil
+}
+
+// deleteSessions removes every web session pointing at uid and returns how many it found.
+func deleteSessions(ctx context.Context, uid string) (int, error) {
+	return scanSessions(ctx, uid, true)
+}
+
+// countSessions returns how many web sessions still point at uid, read-only.
+func countSessions(ctx context.Context, uid string) (int, error) {
+	return scanSessions(ctx, uid, false)
+}
+
+func scanSessions(ctx context.Context, uid string, remove bool) (int, error) {
+	found := 0
+	iter := database.RedisClient.Scan(ctx, 0, "session:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		val, err := database.RedisClient.Get(ctx, key).Result()
+		if err != nil || val != uid {
+			continue
+		}
+		found++
+		if !remove {
+			continue
+		}
+		if err := database.RedisClient.Del(ctx, key).Err(); err != nil {
+			return found, err
+		}
+	}
+	return found, iter.Err()
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 
+	migrations "graph-migrations"
+
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
@@ -37,3 +39,59 @@ func ConnectNeo4j() {
 	Neo4jDriver = driver
 	log.Println("Connected to Neo4j")
 }
+
+// RecordString is the string value of key in r, "" if it is null or missing.
+func RecordString(r *neo4j.Record, key string) string {
+	v, _ := r.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// RecordStrings is the list of strings of key in r, skipping other values.
+func RecordStrings(r *neo4j.Record, key string) []string {
+	v, _ := r.Get(key)
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RecordInt is the integer value of key in r, 0 if it is null or missing.
+func RecordInt(r *neo4j.Record, key string) int64 {
+	v, _ := r.Get(key)
+	n, _ := v.(int64)
+	return n
+}
+
+// NullIfEmpty turns "" into null, so that SET removes the property.
+func NullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NullIfEmptyList turns an empty list into null, like NullIfEmpty.
+func NullIfEmptyList(items []string) any {
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
+// RunMigrations applies pending graph migrations (constraints, indexes, backfills)
+// unless NEO4J_MIGRATE_ON_START=false, e.g. when they are run with graph-migrations/cmd/migrate.
+func RunMigrations() {
+	if os.Getenv("NEO4J_MIGRATE_ON_START") == "false" {
+		return
+	}
+	ran, err := migrations.Run(Ctx, Neo4jDriver, "dashboard-server")
+	if err != nil {
+		log.Fatalf("Failed to apply Neo4j migrations: %v", err)
+	}
+	log.Printf("Neo4j schema up to date (%d migrations applied)", len(ran))
+}
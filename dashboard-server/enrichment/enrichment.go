@@ -0,0 +1,434 @@
+// Package enrichment classifies the posts captured by the plugin in the
+// background: sentiment, topics, language and intent, written back on the
+// Post node.
+//
+// Every run picks the posts not yet classified by the current enrichment
+// prompt version, newest first, and sends them to the model in batches until
+// the run's token budget is spent. Posts classified as hiring then go through
+// job extraction (see jobs.go), and, if enabled, the headlines event-service's
+// rules could not read go through the model (see headlines.go), all within
+// the same budget. Writes are idempotent: classifying a post again replaces
+// its properties and its inferred topics, so shipping a new enrichment
+// template re-processes the graph over the following runs.
+//
+//	ENRICH_ENABLED           start the worker (default: false)
+//	ENRICH_INTERVAL_SECONDS  time between runs (default: 300)
+//	ENRICH_BATCH_SIZE        posts (or headlines) per model call (default: 10, max 25)
+//	ENRICH_TOKEN_BUDGET      input + output tokens per run (default: 50000)
+//	ENRICH_MODEL             model override, e.g. a cheaper one
+//	ENRICH_HEADLINES         also parse unparsed headlines (default: false)
+//
+// The provider for every pass is the one configured for llm.FeatureEnrich
+// (LLM_PROVIDER_ENRICHMENT, LLM_ENRICHMENT_MAX_TOKENS, ...).
+package enrichment
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dashboard-server/database"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/prompts"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Values of Post.sentiment and Post.intent.
+var (
+	Sentiments = []string{"positive", "neutral", "negative"}
+	Intents    = []string{"hiring", "launch", "question", "none"}
+)
+
+const (
+	maxBatchSize = 25
+	maxPostText  = 2000 // runes of each post sent to the model
+	maxTopics    = 5
+)
+
+type config struct {
+	interval    time.Duration
+	batchSize   int
+	tokenBudget int
+	model       string
+	headlines   bool
+}
+
+func loadConfig() config {
+	return config{
+		interval:    time.Duration(envInt("ENRICH_INTERVAL_SECONDS", 300)) * time.Second,
+		batchSize:   min(max(envInt("ENRICH_BATCH_SIZE", 10), 1), maxBatchSize),
+		tokenBudget: envInt("ENRICH_TOKEN_BUDGET", 50000),
+		model:       os.Getenv("ENRICH_MODEL"),
+		headlines:   envBool("ENRICH_HEADLINES"),
+	}
+}
+
+// Start runs the worker until the process exits, if ENRICH_ENABLED is set.
+func Start() {
+	if !envBool("ENRICH_ENABLED") {
+		logger.Info("post enrichment disabled (ENRICH_ENABLED)")
+		return
+	}
+	cfg := loadConfig()
+	logger.Info("post enrichment started", "interval", cfg.interval.String(), "batch_size", cfg.batchSize, "token_budget", cfg.tokenBudget,
+		"headlines", cfg.headlines)
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		run(context.Background(), cfg)
+	}
+}
+
+// runner is a pass over items of any kind.
+type runner interface {
+	run(ctx context.Context, provider llm.Provider, cfg config, budget int) int
+}
+
+// pass is one kind of enrichment: a prompt, the items (posts, people) it
+// still has to process for the prompt's current version, and how a batch is
+// processed.
+type pass[T any] struct {
+	prompt  string
+	pending func(ctx context.Context, version string, limit int) ([]T, error)
+	process func(ctx context.Context, provider llm.Provider, cfg config, version string, batch []T) (int, error)
+}
+
+// passes returns the passes to run, in order: job extraction works on the
+// posts the classification marked as hiring; headline parsing is optional.
+func passes(cfg config) []runner {
+	list := []runner{
+		pass[post]{prompt: prompts.Enrich, pending: pendingPosts, process: enrichBatch},
+		pass[post]{prompt: prompts.Jobs, pending: pendingJobPosts, process: extractJobs},
+	}
+	if cfg.headlines {
+		list = append(list, pass[person]{prompt: prompts.Headlines, pending: pendingHeadlines, process: parseHeadlines})
+	}
+	return list
+}
+
+// run is one enrichment run: every pass in turn, sharing the token budget.
+func run(ctx context.Context, cfg config) {
+	provider := llm.For(llm.FeatureEnrich, "")
+	tokens := 0
+	for _, p := range passes(cfg) {
+		if tokens >= cfg.tokenBudget {
+			break
+		}
+		tokens += p.run(ctx, provider, cfg, cfg.tokenBudget-tokens)
+	}
+}
+
+// run processes batches until nothing is pending, a batch fails or the budget
+// is spent, and returns the tokens used.
+func (p pass[T]) run(ctx context.Context, provider llm.Provider, cfg config, budget int) int {
+	start := time.Now()
+	version, err := prompts.Pick(p.prompt, 0)
+	if err != nil {
+		logger.Error("enrichment prompt unavailable", "err", err, "prompt", p.prompt)
+		return 0
+	}
+
+	var items, tokens, batches int
+	for tokens < budget {
+		batch, err := p.pending(ctx, version, cfg.batchSize)
+		if err != nil {
+			logger.Error("enrichment query failed", "err", err, "prompt", p.prompt)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		used, err := p.process(ctx, provider, cfg, version, batch)
+		tokens += used
+		batches++
+		if err != nil {
+			// Provider errors end the run: the next one retries the same items
+			logger.Error("enrichment batch failed", "err", err, "prompt", p.prompt, "items", len(batch))
+			break
+		}
+		items += len(batch)
+	}
+
+	if batches > 0 {
+		logger.Info("enrichment pass done", "prompt", p.prompt, "items", items, "batches", batches, "tokens", tokens,
+			"budget_exhausted", tokens >= budget, "prompt_version", version,
+			"duration_ms", time.Since(start).Milliseconds())
+	}
+	return tokens
+}
+
+type post struct {
+	Urn  string
+	Text string
+
+	// Job extraction only
+	AuthorName     string
+	AuthorHeadline string
+	Companies      []company // companies mentioned in the post
+}
+
+type company struct {
+	Name string
+	Slug string
+}
+
+// pendingPosts returns posts with text not yet classified by version, nor
+// already failed with it. Newly flushed posts come first.
+func pendingPosts(ctx context.Context, version string, limit int) ([]post, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (p:Post)
+			WHERE p.text IS NOT NULL AND trim(p.text) <> ''
+			  AND coalesce(p.enrichment_version, '') <> $version
+			  AND coalesce(p.enrichment_failed, '') <> $version
+			RETURN p.urn AS urn, left(p.text, $textLen) AS text
+			ORDER BY p.enrichment_version IS NULL DESC, p.first_seen DESC
+			LIMIT $limit
+		`, map[string]any{"version": version, "textLen": maxPostText, "limit": limit})
+		if err != nil {
+			return nil, err
+		}
+		var posts []post
+		for rec.Next(ctx) {
+			r := rec.Record()
+			posts = append(posts, post{Urn: database.RecordString(r, "urn"), Text: database.RecordString(r, "text")})
+		}
+		return posts, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]post), nil
+}
+
+// Classification is the model's answer for one post.
+type Classification struct {
+	Index     int      `json:"index"`
+	Sentiment string   `json:"sentiment"`
+	Topics    []string `json:"topics"`
+	Language  string   `json:"language"`
+	Intent    string   `json:"intent"`
+}
+
+var schema = &llm.Schema{
+	Name:        "classify_posts",
+	Description: "Restituisce sentiment, temi, lingua e intento di ciascun post.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"posts": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"index":     map[string]any{"type": "integer"},
+						"sentiment": map[string]any{"type": "string", "enum": Sentiments},
+						"topics":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+						"language":  map[string]any{"type": "string"},
+						"intent":    map[string]any{"type": "string", "enum": Intents},
+					},
+					"required":             []string{"index", "sentiment", "topics", "language", "intent"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"posts"},
+		"additionalProperties": false,
+	},
+}
+
+// enrichBatch classifies a batch and writes the results, returning the tokens
+// used. Posts the model skipped or answered invalidly for are marked as
+// failed for this version, so they do not eat the budget of every run; a
+// provider error leaves the batch untouched.
+func enrichBatch(ctx context.Context, provider llm.Provider, cfg config, version string, batch []post) (int, error) {
+	data := prompts.Data{}
+	for i, p := range batch {
+		data.Posts = append(data.Posts, prompts.EnrichPost{Index: i + 1, Text: p.Text})
+	}
+	prompt, err := prompts.RenderVersion(prompts.Enrich, version, data)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, llm.RequestTimeout)
+	defer cancel()
+	req := llm.UserRequest(prompt.System, prompt.User)
+	req.Schema = schema
+	req.Model = cfg.model
+	resp, err := provider.Complete(ctx, req)
+	used := resp.InputTokens + resp.OutputTokens
+	if err != nil {
+		return used, err
+	}
+
+	classified := map[string]Classification{}
+	results, parseErr := parse(resp.Text)
+	if parseErr != nil {
+		logger.Warn("invalid enrichment output", "err", parseErr, "prompt_version", version)
+	}
+	for _, c := range results {
+		if c.Index >= 1 && c.Index <= len(batch) {
+			classified[batch[c.Index-1].Urn] = c
+		}
+	}
+	var failed []string
+	for _, p := range batch {
+		if _, ok := classified[p.Urn]; !ok {
+			failed = append(failed, p.Urn)
+		}
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = provider.Name()
+	}
+	return used, save(ctx, version, model, classified, failed)
+}
+
+func parse(text string) ([]Classification, error) {
+	var out struct {
+		Posts []Classification `json:"posts"`
+	}
+	if err := llm.DecodeObject(text, &out); err != nil {
+		return nil, err
+	}
+
+	var valid []Classification
+	for _, c := range out.Posts {
+		if c.normalize() {
+			valid = append(valid, c)
+		}
+	}
+	return valid, nil
+}
+
+// normalize cleans a classification and reports whether it is usable.
+func (c *Classification) normalize() bool {
+	c.Sentiment = strings.ToLower(strings.TrimSpace(c.Sentiment))
+	c.Intent = strings.ToLower(strings.TrimSpace(c.Intent))
+	c.Language = strings.ToLower(strings.TrimSpace(c.Language))
+	if len(c.Language) != 2 {
+		c.Language = ""
+	}
+	if !slices.Contains(Sentiments, c.Sentiment) {
+		return false
+	}
+	if !slices.Contains(Intents, c.Intent) {
+		c.Intent = "none"
+	}
+
+	seen := map[string]bool{}
+	var topics []string
+	for _, t := range c.Topics {
+		t = topicName(t)
+		if t == "" || len(t) > 50 || seen[t] {
+			continue
+		}
+		seen[t] = true
+		topics = append(topics, t)
+		if len(topics) == maxTopics {
+			break
+		}
+	}
+	c.Topics = topics
+	return true
+}
+
+// topicName normalizes a topic: no "#", trimmed and lowercased. event-service
+// names the Topics of hashtags the same way (worker/flush.go).
+func topicName(topic string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(topic), "#")))
+}
+
+// save writes the classifications and marks the failed posts. Inferred topics
+// replace the previous inferred ones; HAS_TOPIC links from hashtags are kept
+// and never flagged. A post whose intent changed goes through job extraction
+// again, and loses its jobs if it is no longer a hiring post. A new Topic
+// inherits introduced_by from its post, so it goes away with the account that
+// brought the post in.
+func save(ctx context.Context, version, model string, classified map[string]Classification, failed []string) error {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	rows := make([]map[string]any, 0, len(classified))
+	for urn, c := range classified {
+		topics := make([]any, len(c.Topics))
+		for i, t := range c.Topics {
+			topics[i] = t
+		}
+		var language any
+		if c.Language != "" {
+			language = c.Language
+		}
+		rows = append(rows, map[string]any{
+			"urn": urn, "sentiment": c.Sentiment, "topics": topics, "language": language, "intent": c.Intent,
+		})
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (p:Post {urn: row.urn})
+			WITH p, row, coalesce(p.intent, '') <> row.intent AS intentChanged
+			SET p.sentiment = row.sentiment, p.intent = row.intent,
+			    p.jobs_version = CASE WHEN intentChanged THEN null ELSE p.jobs_version END,
+			    p.language = coalesce(row.language, p.language),
+			    p.enrichment_version = $version, p.enrichment_model = $model, p.enriched_at = datetime()
+			REMOVE p.enrichment_failed
+			WITH p, row
+			CALL {
+			  WITH p
+			  MATCH (p)-[old:HAS_TOPIC {inferred: true}]->(:Topic)
+			  DELETE old
+			}
+			CALL {
+			  WITH p, row
+			  MATCH (p)-[:ANNOUNCED_IN]->(job:Job)
+			  WHERE row.intent <> 'hiring'
+			  DETACH DELETE job
+			}
+			WITH p, row
+			UNWIND row.topics AS name
+			MERGE (t:Topic {name: name})
+			  ON CREATE SET t.introduced_by = p.introduced_by
+			MERGE (p)-[r:HAS_TOPIC]->(t)
+			  ON CREATE SET r.inferred = true
+		`, map[string]any{"rows": rows, "version": version, "model": model})
+		if err != nil {
+			return nil, err
+		}
+		if len(failed) == 0 {
+			return nil, nil
+		}
+		_, err = tx.Run(ctx, `
+			UNWIND $urns AS urn
+			MATCH (p:Post {urn: urn})
+			SET p.enrichment_failed = $version
+		`, map[string]any{"urns": failed, "version": version})
+		return nil, err
+	})
+	return err
+}
+
+func envBool(key string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(key))
+	return v
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,222 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/prompts"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Headline parsing is the fallback of event-service's rule-based parser
+// (worker/headlines.go there), which marks the headlines it could not read
+// with Person.headline_parser = "unparsed". The model reads them and writes
+// the same shape the rules do, flagged "llm":
+//
+//	(:Person {job_title, seniority})-[:WORKS_AT {title, source: "llm"}]->(:Company)
+//
+// When the headline changes, event-service parses it again and clears
+// headline_llm_version, so the person comes back here only if the rules
+// still fail. Clearing role_taxonomy here has event-service classify the role
+// again from the model's title.
+
+const maxHeadlineText = 300
+
+type person struct {
+	Slug     string
+	Headline string
+}
+
+// pendingHeadlines returns unparsed people not yet read by version, nor
+// already failed with it.
+func pendingHeadlines(ctx context.Context, version string, limit int) ([]person, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (p:Person {headline_parser: 'unparsed'})
+			WHERE p.headline IS NOT NULL
+			  AND coalesce(p.headline_llm_version, '') <> $version
+			  AND coalesce(p.headline_llm_failed, '') <> $version
+			RETURN p.slug AS slug, p.headline AS headline
+			ORDER BY p.headline_parsed_at DESC
+			LIMIT $limit
+		`, map[string]any{"version": version, "limit": limit})
+		if err != nil {
+			return nil, err
+		}
+		var people []person
+		for rec.Next(ctx) {
+			r := rec.Record()
+			people = append(people, person{Slug: database.RecordString(r, "slug"), Headline: database.RecordString(r, "headline")})
+		}
+		return people, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]person), nil
+}
+
+// ParsedHeadline is the model's reading of one headline.
+type ParsedHeadline struct {
+	Index     int    `json:"index"`
+	Title     string `json:"title"`
+	Seniority string `json:"seniority"`
+	Company   string `json:"company"`
+}
+
+var headlinesSchema = &llm.Schema{
+	Name:        "parse_headlines",
+	Description: "Restituisce ruolo, seniority e azienda attuale di ciascun titolo professionale.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"headlines": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"index":     map[string]any{"type": "integer"},
+						"title":     map[string]any{"type": "string"},
+						"seniority": map[string]any{"type": "string", "enum": Seniorities},
+						"company":   map[string]any{"type": "string"},
+					},
+					"required":             []string{"index", "title", "seniority", "company"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"headlines"},
+		"additionalProperties": false,
+	},
+}
+
+// parseHeadlines is the headline pass for one batch, returning the tokens
+// used. People the model skipped are marked as failed for this version.
+func parseHeadlines(ctx context.Context, provider llm.Provider, cfg config, version string, batch []person) (int, error) {
+	data := prompts.Data{}
+	for i, p := range batch {
+		data.Headlines = append(data.Headlines, prompts.Headline{Index: i + 1, Text: truncateRunes(p.Headline, maxHeadlineText)})
+	}
+	prompt, err := prompts.RenderVersion(prompts.Headlines, version, data)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, llm.RequestTimeout)
+	defer cancel()
+	req := llm.UserRequest(prompt.System, prompt.User)
+	req.Schema = headlinesSchema
+	req.Model = cfg.model
+	resp, err := provider.Complete(ctx, req)
+	used := resp.InputTokens + resp.OutputTokens
+	if err != nil {
+		return used, err
+	}
+
+	var out struct {
+		Headlines []ParsedHeadline `json:"headlines"`
+	}
+	if err := llm.DecodeObject(resp.Text, &out); err != nil {
+		logger.Warn("invalid headline parsing output", "err", err, "prompt_version", version)
+	}
+
+	suppressed, err := database.RedisClient.SMembers(ctx, suppressedKey).Result()
+	if err != nil {
+		return used, fmt.Errorf("load suppression list: %w", err)
+	}
+
+	parsed := map[int]map[string]any{}
+	for _, h := range out.Headlines {
+		if h.Index < 1 || h.Index > len(batch) || parsed[h.Index] != nil {
+			continue
+		}
+		h.normalize()
+		slug := slugify(h.Company)
+		if slices.Contains(suppressed, slug) {
+			h.Company, slug = "", ""
+		}
+		p := batch[h.Index-1]
+		parsed[h.Index] = map[string]any{
+			"slug":        p.Slug,
+			"headline":    p.Headline,
+			"title":       database.NullIfEmpty(h.Title),
+			"seniority":   h.Seniority,
+			"company":     database.NullIfEmpty(h.Company),
+			"companySlug": database.NullIfEmpty(slug),
+		}
+	}
+	rows := make([]map[string]any, 0, len(parsed))
+	var failed []string
+	for i, p := range batch {
+		if row, ok := parsed[i+1]; ok {
+			rows = append(rows, row)
+		} else {
+			failed = append(failed, p.Slug)
+		}
+	}
+	return used, saveHeadlines(ctx, version, rows, failed)
+}
+
+// normalize cleans a parsed headline.
+func (h *ParsedHeadline) normalize() {
+	h.Title = truncateRunes(strings.TrimSpace(h.Title), 120)
+	h.Company = strings.TrimSpace(h.Company)
+	if len(h.Company) > 80 {
+		h.Company = ""
+	}
+	h.Seniority = strings.ToLower(strings.TrimSpace(h.Seniority))
+	if !slices.Contains(Seniorities, h.Seniority) {
+		h.Seniority = "unspecified"
+	}
+}
+
+// saveHeadlines writes the parsed headlines, skipping people whose headline
+// changed in the meantime (event-service parses them again first).
+func saveHeadlines(ctx context.Context, version string, rows []map[string]any, failed []string) error {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (p:Person {slug: row.slug})
+			WHERE p.headline = row.headline
+			SET p.job_title = coalesce(row.title, p.job_title), p.seniority = row.seniority,
+			    p.headline_parser = CASE WHEN row.companySlug IS NULL THEN 'unparsed' ELSE 'llm' END,
+			    p.headline_llm_version = $version
+			REMOVE p.headline_llm_failed, p.role_taxonomy
+			WITH p, row
+			WHERE row.companySlug IS NOT NULL
+			MERGE (c:Company {slug: row.companySlug})
+			  ON CREATE SET c.name = row.company, c.inferred = true, c.introduced_by = p.introduced_by
+			MERGE (p)-[w:WORKS_AT]->(c)
+			SET w.title = row.title, w.source = 'llm', w.updated_at = datetime()
+		`, map[string]any{"rows": rows, "version": version})
+		if err != nil || len(failed) == 0 {
+			return nil, err
+		}
+		_, err = tx.Run(ctx, `
+			UNWIND $slugs AS slug
+			MATCH (p:Person {slug: slug})
+			SET p.headline_llm_failed = $version
+		`, map[string]any{"slugs": failed, "version": version})
+		return nil, err
+	})
+	return err
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
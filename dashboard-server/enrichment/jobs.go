@@ -0,0 +1,290 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"dashboard-server/database"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/prompts"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Job extraction turns the posts classified as hiring into
+//
+//	(:Company)-[:HIRING]->(:Job)<-[:ANNOUNCED_IN]-(:Post)
+//
+// The Company is the one the post mentions when the names match, otherwise a
+// Company keyed by a slug derived from the name and flagged inferred. Jobs
+// are rebuilt on every extraction of their post, so re-processing is
+// idempotent. Job.id is "<post urn>#<n>".
+
+// Values of Job.seniority and Job.remote.
+var (
+	Seniorities    = []string{"intern", "junior", "mid", "senior", "lead", "executive", "unspecified"}
+	RemotePolicies = []string{"onsite", "hybrid", "remote", "unspecified"}
+)
+
+const maxJobsPerPost = 10
+
+// suppressedKey is event-service's privacy.SuppressedKey: slugs of people and
+// companies that asked to be removed and must never be written again.
+const suppressedKey = "privacy:suppressed_slugs"
+
+// pendingJobPosts returns hiring posts not yet extracted by version, nor
+// already failed with it.
+func pendingJobPosts(ctx context.Context, version string, limit int) ([]post, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (p:Post {intent: 'hiring'})
+			WHERE p.text IS NOT NULL
+			  AND coalesce(p.jobs_version, '') <> $version
+			  AND coalesce(p.jobs_failed, '') <> $version
+			OPTIONAL MATCH (p)-[:AUTHORED_BY]->(a:Person)
+			WITH p, head(collect(a)) AS a
+			RETURN p.urn AS urn, left(p.text, $textLen) AS text, a.name AS author, a.headline AS headline,
+			       [(p)-[:MENTIONS]->(c:Company) WHERE c.name IS NOT NULL | [c.name, c.slug]] AS companies
+			ORDER BY p.first_seen DESC
+			LIMIT $limit
+		`, map[string]any{"version": version, "textLen": maxPostText, "limit": limit})
+		if err != nil {
+			return nil, err
+		}
+		var posts []post
+		for rec.Next(ctx) {
+			r := rec.Record()
+			p := post{
+				Urn:            database.RecordString(r, "urn"),
+				Text:           database.RecordString(r, "text"),
+				AuthorName:     database.RecordString(r, "author"),
+				AuthorHeadline: database.RecordString(r, "headline"),
+			}
+			companies, _ := r.Get("companies")
+			list, _ := companies.([]any)
+			for _, item := range list {
+				pair, _ := item.([]any)
+				if len(pair) != 2 {
+					continue
+				}
+				name, _ := pair[0].(string)
+				slug, _ := pair[1].(string)
+				p.Companies = append(p.Companies, company{Name: name, Slug: slug})
+			}
+			posts = append(posts, p)
+		}
+		return posts, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]post), nil
+}
+
+// Job is one open position extracted from a post.
+type Job struct {
+	Index     int    `json:"index"`
+	Role      string `json:"role"`
+	Seniority string `json:"seniority"`
+	Location  string `json:"location"`
+	Remote    string `json:"remote"`
+	Company   string `json:"company"`
+}
+
+var jobsSchema = &llm.Schema{
+	Name:        "extract_jobs",
+	Description: "Restituisce le posizioni aperte annunciate in ciascun post.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"jobs": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"index":     map[string]any{"type": "integer"},
+						"role":      map[string]any{"type": "string"},
+						"seniority": map[string]any{"type": "string", "enum": Seniorities},
+						"location":  map[string]any{"type": "string"},
+						"remote":    map[string]any{"type": "string", "enum": RemotePolicies},
+						"company":   map[string]any{"type": "string"},
+					},
+					"required":             []string{"index", "role", "seniority", "location", "remote", "company"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"jobs"},
+		"additionalProperties": false,
+	},
+}
+
+// extractJobs is the job extraction pass for one batch, returning the tokens
+// used. A post the model returned no job for is still marked extracted: the
+// classifier can be wrong, and the post is simply not an opening. Only an
+// unparsable answer marks the batch as failed.
+func extractJobs(ctx context.Context, provider llm.Provider, cfg config, version string, batch []post) (int, error) {
+	data := prompts.Data{}
+	for i, p := range batch {
+		ep := prompts.EnrichPost{Index: i + 1, Text: p.Text, AuthorName: p.AuthorName, AuthorHeadline: p.AuthorHeadline}
+		for _, c := range p.Companies {
+			ep.Companies = append(ep.Companies, c.Name)
+		}
+		data.Posts = append(data.Posts, ep)
+	}
+	prompt, err := prompts.RenderVersion(prompts.Jobs, version, data)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, llm.RequestTimeout)
+	defer cancel()
+	req := llm.UserRequest(prompt.System, prompt.User)
+	req.Schema = jobsSchema
+	req.Model = cfg.model
+	resp, err := provider.Complete(ctx, req)
+	used := resp.InputTokens + resp.OutputTokens
+	if err != nil {
+		return used, err
+	}
+
+	var out struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := llm.DecodeObject(resp.Text, &out); err != nil {
+		logger.Warn("invalid job extraction output", "err", err, "prompt_version", version)
+		urns := make([]string, len(batch))
+		for i, p := range batch {
+			urns[i] = p.Urn
+		}
+		return used, markJobsFailed(ctx, version, urns)
+	}
+
+	suppressed, err := database.RedisClient.SMembers(ctx, suppressedKey).Result()
+	if err != nil {
+		// Like the event flush: without the suppression list nothing is written
+		return used, fmt.Errorf("load suppression list: %w", err)
+	}
+
+	rows := make([]map[string]any, len(batch))
+	for i, p := range batch {
+		rows[i] = map[string]any{"urn": p.Urn, "jobs": []any{}}
+	}
+	for _, j := range out.Jobs {
+		if j.Index < 1 || j.Index > len(batch) || !j.normalize() {
+			continue
+		}
+		p := batch[j.Index-1]
+		row := rows[j.Index-1]
+		slug := companySlug(j.Company, p.Companies)
+		if slices.Contains(suppressed, slug) {
+			j.Company, slug = "", ""
+		}
+		jobs := row["jobs"].([]any)
+		if len(jobs) == maxJobsPerPost {
+			continue
+		}
+		row["jobs"] = append(jobs, map[string]any{
+			"id":          fmt.Sprintf("%s#%d", p.Urn, len(jobs)+1),
+			"role":        j.Role,
+			"seniority":   j.Seniority,
+			"location":    database.NullIfEmpty(j.Location),
+			"remote":      j.Remote,
+			"company":     database.NullIfEmpty(j.Company),
+			"companySlug": database.NullIfEmpty(slug),
+		})
+	}
+	return used, saveJobs(ctx, version, rows)
+}
+
+// normalize cleans a job and reports whether it is usable.
+func (j *Job) normalize() bool {
+	j.Role = strings.TrimSpace(j.Role)
+	j.Location = strings.TrimSpace(j.Location)
+	j.Company = strings.TrimSpace(j.Company)
+	j.Seniority = strings.ToLower(strings.TrimSpace(j.Seniority))
+	j.Remote = strings.ToLower(strings.TrimSpace(j.Remote))
+	if !slices.Contains(Seniorities, j.Seniority) {
+		j.Seniority = "unspecified"
+	}
+	if !slices.Contains(RemotePolicies, j.Remote) {
+		j.Remote = "unspecified"
+	}
+	return j.Role != "" && len(j.Role) <= 200
+}
+
+// companySlug is the slug of the mentioned company with the same name, or one
+// derived from the name.
+func companySlug(name string, mentioned []company) string {
+	if name == "" {
+		return ""
+	}
+	for _, c := range mentioned {
+		if c.Slug != "" && strings.EqualFold(c.Name, name) {
+			return c.Slug
+		}
+	}
+	return slugify(name)
+}
+
+// slugify lowercases name and joins its letters and digits with dashes, the
+// way LinkedIn company slugs usually look.
+func slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+func saveJobs(ctx context.Context, version string, rows []map[string]any) error {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (p:Post {urn: row.urn})
+			SET p.jobs_version = $version, p.jobs_extracted_at = datetime()
+			REMOVE p.jobs_failed
+			WITH p, row
+			CALL {
+			  WITH p
+			  MATCH (p)-[:ANNOUNCED_IN]->(old:Job)
+			  DETACH DELETE old
+			}
+			WITH p, row
+			UNWIND row.jobs AS job
+			CREATE (p)-[:ANNOUNCED_IN]->(j:Job {id: job.id, role: job.role, seniority: job.seniority,
+			  location: job.location, remote: job.remote, company_name: job.company,
+			  announced_at: p.first_seen, introduced_by: p.introduced_by, extraction_version: $version})
+			FOREACH (_ IN CASE WHEN job.companySlug IS NOT NULL THEN [1] ELSE [] END |
+			  MERGE (c:Company {slug: job.companySlug})
+			    ON CREATE SET c.name = job.company, c.inferred = true, c.introduced_by = p.introduced_by
+			  MERGE (c)-[:HIRING]->(j))
+		`, map[string]any{"rows": rows, "version": version})
+		return nil, err
+	})
+	return err
+}
+
+func markJobsFailed(ctx context.Context, version string, urns []string) error {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $urns AS urn
+			MATCH (p:Post {urn: urn})
+			SET p.jobs_failed = $version
+		`, map[string]any{"urns": urns, "version": version})
+		return nil, err
+	})
+	return err
+}
@@ -7,6 +7,7 @@ import (
 
 	"dashboard-server/database"
 	"dashboard-server/logger"
+	"dashboard-server/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -25,7 +26,7 @@ type ActivityItem struct {
 // GetActivity handles GET /api/activity?limit=20&offset=0
 // Returns the most recent activity events for the authenticated user.
 func GetActivity(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	limit := c.QueryInt("limit", 20)
 	offset := c.QueryInt("offset", 0)
 	start := time.Now()
@@ -1,311 +1,371 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
+
 	"dashboard-server/database"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"time"
+	"dashboard-server/i18n"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/prompts"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
-const (
-	AnthropicVersion = "2023-06-01"
-	AnthropicURL     = "https://api.anthropic.com/v1/messages"
-	AnthropicModel   = "claude-sonnet-4-5"
-)
-
-type AnthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	System    string             `json:"system"`
-	Messages  []AnthropicMessage `json:"messages"`
-}
-
-type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type AnthropicResponse struct {
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	Error *struct {
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
-}
-
 type AIRequest struct {
+	PostUrn             string      `json:"postUrn,omitempty"`
+	AuthorSlug          string      `json:"authorSlug,omitempty"` // graph lookup when the post is not ingested yet
 	PostText            string      `json:"postText"`
 	CommentsText        string      `json:"commentsText"`
 	ProfessionalContext string      `json:"professionalContext,omitempty"`
 	UserName            string      `json:"userName,omitempty"`
 	Purposes            []string    `json:"purposes,omitempty"`
 	TriggerData         interface{} `json:"triggerData,omitempty"`
+
+	// Comment generation only (see regenerate.go)
+	Variants       int    `json:"variants,omitempty"`       // alternative comments to write, up to MaxVariants
+	RegenerateFrom string `json:"regenerateFrom,omitempty"` // generation_id of the comment to rewrite
+	Instruction    string `json:"instruction,omitempty"`    // shorter, more_technical, add_question or free text
 }
 
-func callAnthropic(system string, userContent string) (string, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("ANTHROPIC_API_KEY non configurata sul server")
-	}
+func SuggestPurposes(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
 
-	reqBody := AnthropicRequest{
-		Model:     AnthropicModel,
-		MaxTokens: 2048,
-		System:    system,
-		Messages: []AnthropicMessage{
-			{Role: "user", Content: userContent},
-		},
+	var req AIRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
 	}
 
-	jsonBody, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("POST", AnthropicURL, bytes.NewBuffer(jsonBody))
+	ctx, cancel := aiContext(c)
+	defer cancel()
+	prompt, err := prompts.Render(prompts.Purposes, userID, promptData(ctx, userID, req, lang))
 	if err != nil {
-		return "", err
+		logger.Error("prompt render failed", "err", err, "prompt", prompts.Purposes)
+		return c.Status(500).JSON(fiber.Map{"error": i18n.T(lang, "error.prompt")})
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", AnthropicVersion)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	tier, _ := c.Locals("tier").(string)
+	provider, _ := resolveProvider(ctx, userID, llm.FeaturePurposes, tier)
+	result, _, err := completeStructured[PurposesResult](ctx, provider, llm.UserRequest(prompt.System, prompt.User), purposesSchema, prompt.Language)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Anthropic API error: %d - %s", resp.StatusCode, string(body))
-	}
-
-	var anthropicResp AnthropicResponse
-	if err := json.Unmarshal(body, &anthropicResp); err != nil {
-		return "", err
-	}
-
-	if len(anthropicResp.Content) > 0 && anthropicResp.Content[0].Type == "text" {
-		return anthropicResp.Content[0].Text, nil
+		logger.Error("purposes generation failed", "err", err, "user_id", userID, "prompt_version", prompt.Version)
+		return aiErrorResponse(c, lang, err)
 	}
-
-	return "", fmt.Errorf("Risposta Anthropic vuota o non valida")
+	return c.JSON(result)
 }
 
-func SuggestPurposes(c *fiber.Ctx) error {
+func GenerateComment(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
+
 	var req AIRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
 	}
+	req.Variants = min(max(req.Variants, 1), MaxVariants)
 
-	hasProfessionalContext := req.ProfessionalContext != ""
-	systemPrompt := getSystemPrompt("purposes", hasProfessionalContext, req.UserName)
-	userPrompt := buildPurposesPrompt(req)
+	tier, _ := c.Locals("tier").(string)
 
-	result, err := callAnthropic(systemPrompt, userPrompt)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	ctx, cancel := aiContext(c)
+	defer cancel()
+	prev, status, body := commentSession(ctx, userID, &req, lang)
+	if status != 0 {
+		return c.Status(status).JSON(body)
 	}
 
-	// Tentativo di parsing JSON robusto
-	jsonStr := extractJSON(result)
-	var parsedResponse interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &parsedResponse); err == nil {
-		return c.JSON(parsedResponse)
+	// Enforcement for Free tier (users on their own API key are not capped).
+	// A regeneration belongs to an already metered session.
+	provider, byok := resolveProvider(ctx, userID, llm.FeatureComment, tier)
+	if !byok && prev == nil && limitReached(userID, tier) {
+		return c.Status(403).JSON(limitReachedResponse(lang))
 	}
 
-	return c.JSON(fiber.Map{"raw": result, "purposes": []string{}})
-}
-
-func GenerateComment(c *fiber.Ctx) error {
-	var req AIRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	data := promptData(ctx, userID, req, lang)
+	data.Variants = req.Variants
+	if prev != nil {
+		data.Regenerate = regeneration(prev, req.Instruction)
+	}
+	prompt, err := prompts.Render(prompts.Comment, userID, data)
+	if err != nil {
+		logger.Error("prompt render failed", "err", err, "prompt", prompts.Comment)
+		return c.Status(500).JSON(fiber.Map{"error": i18n.T(lang, "error.prompt")})
 	}
 
-	userID := fmt.Sprint(c.Locals("user_id"))
-	tier, _ := c.Locals("tier").(string)
+	// Templates before comment/v5 ignore Variants, so Render serves variants
+	// with the latest version; the schema enforces the shape of the answer.
+	var variants []CommentVariant
+	if req.Variants > 1 {
+		result, _, err := completeStructured[VariantsResult](ctx, provider, llm.UserRequest(prompt.System, prompt.User), variantsSchema, prompt.Language)
+		if err != nil {
+			logger.Error("comment variants generation failed", "err", err, "user_id", userID, "prompt_version", prompt.Version)
+			return aiErrorResponse(c, lang, err)
+		}
+		variants = result.Variants[:min(len(result.Variants), req.Variants)]
+	} else {
+		result, _, err := completeStructured[CommentResult](ctx, provider, llm.UserRequest(prompt.System, prompt.User), commentSchema, prompt.Language)
+		if err != nil {
+			logger.Error("comment generation failed", "err", err, "user_id", userID, "prompt_version", prompt.Version)
+			return aiErrorResponse(c, lang, err)
+		}
+		variants = []CommentVariant{{CommentResult: result}}
+	}
 
-	// Enforcement for Free tier
-	if tier == "" || tier == "free" {
-		ctx := context.Background()
-		session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-		defer session.Close(ctx)
-
-		result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-			query := `
-			MATCH (u:User {id: $userId})-[a:ACTION]->(p:Post)
-			WHERE a.type = 'comment_generated' AND date(datetime(a.timestamp)) = date()
-			RETURN count(a) AS count
-			`
-			rec, err := tx.Run(ctx, query, map[string]any{"userId": userID})
-			if err != nil {
-				return int64(0), err
-			}
-			if rec.Next(ctx) {
-				count, _ := rec.Record().Get("count")
-				return count.(int64), nil
-			}
-			return int64(0), nil
-		})
-
-		if err == nil && result.(int64) >= FreeDailyLimit {
-			return c.Status(403).JSON(fiber.Map{
-				"error":            "Limit reached",
-				"is_limit_reached": true,
-				"message":          "Hai raggiunto il limite di 5 commenti per oggi. Passa a Pro per commenti illimitati.",
-			})
+	// Record the generations in Neo4j: their sessions are what the free-tier
+	// limit counts, and their IDs let the plugin report the outcome (see
+	// ReportOutcome) or ask for a regeneration
+	resp := CommentResponse{CommentResult: variants[0].CommentResult}
+	if prev != nil {
+		resp.SessionID = prev.SessionID
+	}
+	for i, v := range variants {
+		g := commentGeneration(userID, req, v.CommentResult, prompt, lang, byok)
+		g.SessionID = resp.SessionID
+		if len(variants) > 1 {
+			g.Variant, g.Angle = i+1, v.Angle
+		}
+		generationID, sessionID, err := recordGeneration(g)
+		if err != nil {
+			logger.Error("failed to record generation", "err", err, "user_id", userID)
+		}
+		if resp.SessionID == "" {
+			resp.SessionID = sessionID
+		}
+		if i == 0 {
+			resp.GenerationID = generationID
+		}
+		if len(variants) > 1 {
+			resp.Variants = append(resp.Variants, CommentVariantResponse{CommentVariant: v, GenerationID: generationID})
 		}
 	}
+	return c.JSON(resp)
+}
 
-	systemPrompt := getSystemPrompt("comment", false, req.UserName)
-	userPrompt := buildCommentPrompt(req)
+// commentSession resolves req.RegenerateFrom: the generation being rewritten,
+// or nil for a new session. A regeneration works on the post of the previous
+// generation, as stored: the post text sent by the plugin is ignored. A
+// non-zero status is the error response to send.
+func commentSession(ctx context.Context, userID int64, req *AIRequest, lang string) (*previousGeneration, int, fiber.Map) {
+	req.Instruction = normalizeInstruction(req.Instruction)
+	if req.RegenerateFrom == "" {
+		return nil, 0, nil
+	}
 
-	result, err := callAnthropic(systemPrompt, userPrompt)
+	prev, err := loadPreviousGeneration(ctx, userID, req.RegenerateFrom)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		logger.Error("generation lookup failed", "err", err, "user_id", userID, "generation_id", req.RegenerateFrom)
+		return nil, 500, fiber.Map{"error": "Neo4j query failed"}
 	}
-
-	// Tentativo di parsing JSON robusto
-	jsonStr := extractJSON(result)
-	var parsedResponse interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &parsedResponse); err == nil {
-		// Log the action to Neo4j to increment the count
-		go logAIAction(userID, "comment_generated")
-		return c.JSON(parsedResponse)
+	if prev == nil {
+		return nil, 404, fiber.Map{"error": i18n.T(lang, "error.generation_not_found")}
 	}
+	if prev.Generations+int64(req.Variants) > MaxSessionGenerations {
+		return nil, 403, fiber.Map{
+			"error":   "Session limit reached",
+			"message": i18n.T(lang, "error.session_limit_reached", MaxSessionGenerations),
+		}
+	}
+	// A regeneration rides on a session already metered today, for the same
+	// post: anything else would be a new use of the free tier.
+	if !prev.StartedToday || req.PostUrn != "" && req.PostUrn != prev.PostUrn {
+		return nil, 403, fiber.Map{"error": i18n.T(lang, "error.regeneration_not_allowed")}
+	}
+	req.PostUrn = prev.PostUrn
+	if prev.PostText != "" {
+		req.PostText = prev.PostText
+	}
+	return prev, 0, nil
+}
 
-	return c.JSON(fiber.Map{"comment": result})
+// aiContext bounds an AI call, retries included, by llm.RequestTimeout.
+func aiContext(c *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.UserContext(), llm.RequestTimeout)
 }
 
-func logAIAction(userID string, actionType string) {
-	// We use the event-service pattern but here we can just write a quick ACTION node
-	// Better: Use a simple Cypher query if we have Neo4j access
+// limitReached reports whether a free-tier user already used today's AI
+// generations (comments, replies and posts), counted by session (see
+// regenerate.go). Paid tiers are never limited; on query errors the user is
+// let through.
+func limitReached(userID int64, tier string) bool {
+	if tier != "" && tier != "free" {
+		return false
+	}
+
 	ctx := context.Background()
-	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
-	session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		query := `
-		MERGE (u:User {id: $userId})
-		CREATE (u)-[:ACTION {type: $action, timestamp: $ts}]->(:Post {urn: 'ai_gen_internal'})
+		MATCH (u:User {id: $userId})-[:GENERATED]->(g:Generation)
+		WHERE g.type IN $types AND date(g.created_at) = date() AND NOT coalesce(g.byok, false)
+		RETURN count(DISTINCT coalesce(g.session_id, g.id)) AS count
 		`
-		tx.Run(ctx, query, map[string]any{
-			"userId": userID,
-			"action": actionType,
-			"ts":     time.Now().Format(time.RFC3339),
-		})
-		return nil, nil
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID, "types": meteredGenerationTypes})
+		if err != nil {
+			return int64(0), err
+		}
+		if rec.Next(ctx) {
+			count, _ := rec.Record().Get("count")
+			return count.(int64), nil
+		}
+		return int64(0), nil
 	})
-}
 
-func extractJSON(s string) string {
-	start := bytes.IndexByte([]byte(s), '{')
-	end := bytes.LastIndexByte([]byte(s), '}')
-	if start != -1 && end != -1 && end > start {
-		return s[start : end+1]
-	}
-	return s
+	return err == nil && result.(int64) >= FreeDailyLimit
 }
 
-func getSystemPrompt(mode string, hasProfessionalContext bool, userName string) string {
-	if mode == "purposes" {
-		contextInstruction := ""
-		if hasProfessionalContext {
-			contextInstruction = ` Se ti viene fornito un "Contesto professionale dell'utente", il PRIMO scopo nella lista "purposes" deve essere uno scopo suggerito in base a quel contesto (es. coerente con il ruolo, le competenze o gli obiettivi professionali dell'utente); gli altri scopi restano generici e utili per il post.`
-		}
-		identityConstraint := ""
-		if userName != "" {
-			identityConstraint = fmt.Sprintf(`\n- IDENTITÀ UTENTE: L'utente si chiama "%s". NON suggerire MAI di rispondere ai commenti scritti da "%s". Se vedi un suo commento, ignoralo nella scelta dell'opzione strategica.`, userName, userName)
-		}
+// meteredGenerationTypes are the Generation types the free-tier limit counts.
+var meteredGenerationTypes = []string{"comment", "reply", "post"}
 
-		return `Sei un assistente esperto di personal branding su LinkedIn. Il tuo obiettivo è aiutare l'utente a generare engagement di valore e lead B2B attraverso i commenti.
-Il tuo compito è analizzare il testo del post (e eventuali commenti esistenti) e:
-1. Estrapolare un titolo sintetico dell'annuncio/post (max 10-12 parole).
-2. Proporre 3-5 possibili SCOPI per un commento.
-3. LOGICA STRATEGICA (Obbligatoria): Analizza i commenti esistenti. Uno degli scopi (preferibilmente il secondo) deve essere una "Opzione Strategica".
-   - Identifica il commento che merita di più una risposta per aprire un thread di valore (es. un commento con un insight, una domanda o da un profilo rilevante).
-   - Inserisci uno scopo nel formato: "Strategico: Rispondi a [Nome Autore] con un [Tipo di intervento: insight/domanda/esperienza] su [Argomento specifico] per stimolare la discussione".
-   - Segui i principi del network strategico: valore aggiunto (>10 parole), domande aperte, costruzione di catene di conversazione.
-   - Se non ci sono commenti, l'opzione strategica deve suggerire come stimolare la prima discussione sul post stesso.` + identityConstraint + `
-
-` + contextInstruction + `
-Rispondi SOLO con un JSON valido, senza altro testo prima o dopo, nel formato:
-{"title": "Titolo sintetico del post", "purposes": ["Scopo 1", "Scopo 2", "Scopo 3", ...]}
-Ogni scopo deve essere una breve frase in italiano.`
+func limitReachedResponse(lang string) fiber.Map {
+	return fiber.Map{
+		"error":            "Limit reached",
+		"is_limit_reached": true,
+		"message":          i18n.T(lang, "error.limit_reached", FreeDailyLimit),
 	}
+}
 
-	return `Sei un assistente che scrive commenti professionali e naturali per post LinkedIn.
-Il tuo compito è scrivere UN SOLO commento rispettando le seguenti regole:
-
-1. LINGUA: Identifica la lingua del post (o del commento a cui si risponde) e scrivi il commento NELLA STESSA LINGUA.
-2. TRADUZIONE: Fornisci SEMPRE anche una traduzione in ITALIANO del commento che hai generato.
-3. FORMATO: Rispondi ESCLUSIVAMENTE con un oggetto JSON valido (niente testo prima o dopo) nel seguente formato:
-   {"comment": "testo del commento nella lingua originale", "translation": "traduzione del commento in italiano"}
-
-REGOLE DI SCRITTURA (nel commento originale):
-- Breve (2-4 frasi), tono professionale ma umano.
-- Nessun emoji e nessun simbolo decorativo.
-- Nessun trattino lungo (—, –, o simili): non usare il trattino per introdurre incisi o secondarie. Usa invece virgole, due punti, punto e virgola o frasi separate con il punto.
-- Usa solo punteggiatura grammaticale standard.
-- Non ringraziare l'autore del post a meno che il post non condivida un link a una risorsa o parli di esperienze personali. In tutti gli altri casi, entra direttamente nel merito senza preamboli.`
+type generationRecord struct {
+	UserID        int64
+	Type          string // "comment", "reply" or "post"
+	PostUrn       string
+	BYOK          bool   // generated with the user's own API key: not metered
+	PromptVersion string // prompts version that produced it, for A/B comparisons
+	Language      string // user language the output was asked in
+	PostLanguage  string // detected language of the post, "" if unknown
+	Purposes      []string
+	Text          string // generated text, compared with what the user posted
+
+	SessionID       string // metering session; "" starts a new one
+	RegeneratedFrom string // generation this one rewrites
+	Instruction     string // regeneration instruction
+	Variant         int    // 1-based position among the variants of a request, 0 if alone
+	Angle           string // angle of the variant
+	ReplyTo         string // URN of the comment a reply answers
 }
 
-func buildPurposesPrompt(req AIRequest) string {
-	text := fmt.Sprintf("Testo del post:\n%s\n", req.PostText)
-	if req.CommentsText != "" {
-		text += fmt.Sprintf("\nCommenti esistenti (per contesto):\n%s\n", req.CommentsText)
+func commentGeneration(userID int64, req AIRequest, result CommentResult, prompt prompts.Rendered, lang string, byok bool) generationRecord {
+	return generationRecord{
+		UserID:        userID,
+		Type:          "comment",
+		PostUrn:       req.PostUrn,
+		BYOK:          byok,
+		PromptVersion: prompt.Version,
+		Language:      lang,
+		PostLanguage:  i18n.Detect(req.PostText),
+		Purposes:      req.Purposes,
+		Text:          result.Comment,
+
+		RegeneratedFrom: req.RegenerateFrom,
+		Instruction:     req.Instruction,
 	}
+}
 
-	// Gestione triggerData (se presente)
-	if req.TriggerData != nil {
-		if td, ok := req.TriggerData.(map[string]interface{}); ok {
-			isCommenter, _ := td["isCommenter"].(bool)
-			authorName, _ := td["authorName"].(string)
-			commentText, _ := td["commentText"].(string)
-			if isCommenter {
-				text += fmt.Sprintf("\nATTENZIONE: L'utente target %s ha commentato questo post con: \"%s\".\n", authorName, commentText)
-				text += "DEVI includere come priorità (Opzione Strategica) una risposta diretta a questo commento specifico.\n"
-			}
+// recordGeneration stores an AI generation as
+// (:User)-[:GENERATED]->(:Generation)-[:FOR_POST]->(:Post) and returns its ID
+// and session ID. The Post is linked by URN only, never created or written:
+// event-service stores posts under the privacy settings, so the URN is also
+// kept on the generation for posts it has not stored. A regeneration is also
+// linked -[:REGENERATED_FROM]-> the generation it rewrites. The user's node
+// is never created here, so a deleted account cannot come back through a late
+// generation.
+func recordGeneration(g generationRecord) (string, string, error) {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	ids, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (u:User {id: $userId})
+		CREATE (u)-[:GENERATED]->(g:Generation {id: randomUUID(), type: $type, byok: $byok, prompt_version: $promptVersion,
+		  language: $language, post_language: $postLanguage, purposes: $purposes, text: $text, created_at: datetime(),
+		  instruction: $instruction, variant: $variant, angle: $angle, reply_to: $replyTo, post_urn: $postUrn})
+		SET g.session_id = coalesce($sessionId, g.id)
+		WITH u, g
+		OPTIONAL MATCH (u)-[:GENERATED]->(prev:Generation {id: $regeneratedFrom})
+		FOREACH (_ IN CASE WHEN prev IS NOT NULL THEN [1] ELSE [] END |
+		  MERGE (g)-[:REGENERATED_FROM]->(prev))
+		WITH g
+		OPTIONAL MATCH (p:Post {urn: $postUrn})
+		FOREACH (_ IN CASE WHEN p IS NOT NULL THEN [1] ELSE [] END |
+		  MERGE (g)-[:FOR_POST]->(p))
+		RETURN g.id AS id, g.session_id AS session_id
+		`
+		params := map[string]any{
+			"userId":          g.UserID,
+			"type":            g.Type,
+			"postUrn":         database.NullIfEmpty(g.PostUrn),
+			"byok":            g.BYOK,
+			"promptVersion":   g.PromptVersion,
+			"language":        g.Language,
+			"postLanguage":    database.NullIfEmpty(g.PostLanguage),
+			"purposes":        database.NullIfEmptyList(g.Purposes),
+			"text":            database.NullIfEmpty(g.Text),
+			"sessionId":       database.NullIfEmpty(g.SessionID),
+			"regeneratedFrom": g.RegeneratedFrom,
+			"instruction":     database.NullIfEmpty(g.Instruction),
+			"variant":         nil,
+			"angle":           database.NullIfEmpty(g.Angle),
+			"replyTo":         database.NullIfEmpty(g.ReplyTo),
+		}
+		if g.Variant > 0 {
+			params["variant"] = g.Variant
+		}
+		rec, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
 		}
+		if rec.Next(ctx) {
+			r := rec.Record()
+			return [2]string{database.RecordString(r, "id"), database.RecordString(r, "session_id")}, nil
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errUserNotFound
+	})
+	if err != nil {
+		return "", "", err
 	}
+	out := ids.([2]string)
+	return out[0], out[1], nil
+}
 
-	if req.ProfessionalContext != "" {
-		text += fmt.Sprintf("\nContesto professionale dell'utente (usa per proporre come primo scopo un suggerimento coerente con questo profilo):\n%s\n", req.ProfessionalContext)
+// promptData maps the plugin request, the user's saved settings and the graph
+// context of the post to the prompt template fields. The saved profile fills
+// in the professional context when the plugin does not send one.
+func promptData(ctx context.Context, userID int64, req AIRequest, lang string) prompts.Data {
+	profile := userStyleProfile(userID)
+	data := prompts.Data{
+		PostText:            req.PostText,
+		CommentsText:        req.CommentsText,
+		ProfessionalContext: req.ProfessionalContext,
+		UserName:            req.UserName,
+		Purposes:            req.Purposes,
+		Language:            lang,
+		Profile:             profile.prompt(),
+	}
+	if data.ProfessionalContext == "" && profile != nil {
+		data.ProfessionalContext = profile.professionalContext()
 	}
-	text += "\nProponi 3-5 possibili scopi per un commento a questo post (solo JSON come indicato)."
-	return text
-}
 
-func buildCommentPrompt(req AIRequest) string {
-	text := fmt.Sprintf("Testo del post:\n%s\n", req.PostText)
-	if req.CommentsText != "" {
-		text += fmt.Sprintf("\nCommenti esistenti:\n%s\n", req.CommentsText)
+	graph, err := graphContext(ctx, userID, req.PostUrn, req.AuthorSlug)
+	if err != nil {
+		logger.Warn("failed to load graph context", "err", err, "user_id", userID, "post_urn", req.PostUrn)
 	}
+	data.Graph = graph
 
-	// Gestione triggerData per commento
-	if req.TriggerData != nil {
-		if td, ok := req.TriggerData.(map[string]interface{}); ok {
-			isCommenter, _ := td["isCommenter"].(bool)
-			authorName, _ := td["authorName"].(string)
-			commentText, _ := td["commentText"].(string)
-			if isCommenter {
-				text += fmt.Sprintf("\nL'utente ha scelto di rispondere specificamente a %s, che ha scritto: \"%s\".\n", authorName, commentText)
-			}
+	// Gestione triggerData (se presente)
+	if td, ok := req.TriggerData.(map[string]interface{}); ok {
+		isCommenter, _ := td["isCommenter"].(bool)
+		authorName, _ := td["authorName"].(string)
+		commentText, _ := td["commentText"].(string)
+		if isCommenter {
+			data.Trigger = &prompts.Trigger{AuthorName: authorName, CommentText: commentText}
 		}
 	}
-
-	purposesStr, _ := json.Marshal(req.Purposes)
-	text += fmt.Sprintf("\nScopo/i scelto/i per il commento: %s\n", string(purposesStr))
-	text += "\nGenera il commento e la relativa traduzione in italiano (solo JSON come indicato). Se stai rispondendo a un commento, scrivi una risposta diretta e naturale nella lingua del commento originale."
-	return text
+	return data
 }
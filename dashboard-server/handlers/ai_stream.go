@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"dashboard-server/i18n"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/prompts"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GenerateCommentStream handles POST /api/ai/generate-comment/stream.
+// Same input and free-tier limit as GenerateComment, but the answer is sent as
+// Server-Sent Events. Regeneration is supported, variants are not: the stream
+// carries a single comment.
+//
+//	event: delta  data: {"text": "..."}               one per token chunk
+//	event: done   data: {"comment": "...", "translation": "...", "generation_id": "...", "session_id": "..."}
+//	event: error  data: {"error": "...", "code": "provider_error|invalid_output"}
+//
+// The limit check happens before the stream starts, so a 403 is still a plain JSON response.
+func GenerateCommentStream(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
+
+	var req AIRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
+	}
+
+	if req.Variants > 1 {
+		return c.Status(400).JSON(fiber.Map{"error": "variants are not supported when streaming"})
+	}
+	req.Variants = 1
+
+	tier, _ := c.Locals("tier").(string)
+
+	prev, status, body := commentSession(c.UserContext(), userID, &req, lang)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
+	provider, byok := resolveProvider(c.UserContext(), userID, llm.FeatureComment, tier)
+	if !byok && prev == nil && limitReached(userID, tier) {
+		return c.Status(403).JSON(limitReachedResponse(lang))
+	}
+
+	data := promptData(c.UserContext(), userID, req, lang)
+	if prev != nil {
+		data.Regenerate = regeneration(prev, req.Instruction)
+	}
+	prompt, err := prompts.Render(prompts.Comment, userID, data)
+	if err != nil {
+		logger.Error("prompt render failed", "err", err, "prompt", prompts.Comment)
+		return c.Status(500).JSON(fiber.Map{"error": i18n.T(lang, "error.prompt")})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// The fiber.Ctx must not be used inside the stream writer: it runs after the handler returned.
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), llm.RequestTimeout)
+		defer cancel()
+		llmReq := llm.UserRequest(prompt.System, prompt.User)
+		resp, err := provider.Stream(ctx, llmReq, func(text string) error {
+			return writeSSE(w, "delta", fiber.Map{"text": text})
+		})
+		if err != nil {
+			logger.Error("comment stream failed", "err", err, "user_id", userID)
+			writeSSE(w, "error", aiErrorBody(lang, err))
+			return
+		}
+
+		// The streamed text is free-form: validate it like GenerateComment
+		// does, repairing it (without streaming) if needed.
+		result, parseErr := parseStructured[CommentResult](resp.Text)
+		if parseErr != nil {
+			logger.Warn("invalid streamed comment, retrying", "err", parseErr, "user_id", userID)
+			llmReq.Schema = localized(commentSchema, prompt.Language)
+			result, _, err = repairStructured[CommentResult](ctx, provider, llmReq, resp.Text, parseErr, prompt.Language)
+			if err != nil {
+				logger.Error("comment stream repair failed", "err", err, "user_id", userID)
+				writeSSE(w, "error", aiErrorBody(lang, err))
+				return
+			}
+		}
+
+		g := commentGeneration(userID, req, result, prompt, lang, byok)
+		if prev != nil {
+			g.SessionID = prev.SessionID
+		}
+		generationID, sessionID, err := recordGeneration(g)
+		if err != nil {
+			logger.Error("failed to record generation", "err", err, "user_id", userID)
+		}
+		writeSSE(w, "done", CommentResponse{CommentResult: result, GenerationID: generationID, SessionID: sessionID})
+	})
+	return nil
+}
+
+// writeSSE writes one Server-Sent Event and flushes it to the client.
+// An error means the client went away.
+func writeSSE(w *bufio.Writer, event string, data any) error {
+	payload, _ := json.Marshal(data)
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
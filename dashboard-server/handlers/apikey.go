@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dashboard-server/i18n"
+	"dashboard-server/keyvault"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyRequest struct {
+	Provider string `json:"provider"` // "anthropic" | "openai"
+	APIKey   string `json:"api_key"`
+	BaseURL  string `json:"base_url,omitempty"`
+}
+
+type APIKeyStatus struct {
+	Configured bool       `json:"configured"`
+	Provider   string     `json:"provider,omitempty"`
+	Last4      string     `json:"last4,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// resolveProvider returns the provider for a feature: the user's own key when
+// one is stored (byok = true, no free-tier cap), the managed one otherwise.
+func resolveProvider(ctx context.Context, userID int64, feature, tier string) (provider llm.Provider, byok bool) {
+	key, err := keyvault.Load(ctx, userID)
+	if err == nil {
+		p, err := llm.ForKey(feature, key.Provider, key.APIKey, key.BaseURL)
+		if err == nil {
+			return p, true
+		}
+		logger.Warn("stored API key unusable, falling back to managed key", "err", err, "user_id", userID)
+	} else if !errors.Is(err, keyvault.ErrNotFound) {
+		logger.Error("failed to load stored API key", "err", err, "user_id", userID)
+	}
+	return llm.For(feature, tier), false
+}
+
+// GetAPIKey handles GET /api/user/api-key — never returns the key itself.
+func GetAPIKey(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	key, err := keyvault.Load(c.UserContext(), userID)
+	if errors.Is(err, keyvault.ErrNotFound) {
+		return c.JSON(APIKeyStatus{Configured: false})
+	}
+	if err != nil {
+		logger.Error("api key load failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read API key"})
+	}
+	return c.JSON(APIKeyStatus{Configured: true, Provider: key.Provider, Last4: key.Last4, CreatedAt: &key.CreatedAt})
+}
+
+// SetAPIKey handles PUT /api/user/api-key. The key is verified with a minimal
+// call before being stored, so a typo does not silently break generation.
+func SetAPIKey(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	var req APIKeyRequest
+	if err := c.BodyParser(&req); err != nil || req.APIKey == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "api_key is required"})
+	}
+	if req.Provider == "" {
+		req.Provider = "anthropic"
+	}
+	if req.BaseURL != "" {
+		if err := llm.CheckBaseURL(req.BaseURL); err != nil {
+			logger.Warn("api key base_url rejected", "err", err, "user_id", userID)
+			return c.Status(400).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.invalid_base_url")})
+		}
+	}
+
+	if err := testAPIKey(c.UserContext(), req.Provider, req.APIKey, req.BaseURL); err != nil {
+		logger.Warn("api key rejected", "err", err, "user_id", userID, "provider", req.Provider)
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error."+llm.ErrorKind(err))})
+	}
+
+	err := keyvault.Save(c.UserContext(), userID, keyvault.StoredKey{
+		Provider: req.Provider,
+		APIKey:   req.APIKey,
+		BaseURL:  req.BaseURL,
+	})
+	if err != nil {
+		logger.Error("api key save failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to store API key"})
+	}
+
+	logger.Info("api key stored", "user_id", userID, "provider", req.Provider)
+	return GetAPIKey(c)
+}
+
+// TestAPIKey handles POST /api/user/api-key/test: checks the stored key still works.
+func TestAPIKey(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	key, err := keyvault.Load(c.UserContext(), userID)
+	if errors.Is(err, keyvault.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "No API key stored"})
+	}
+	if err != nil {
+		logger.Error("api key load failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read API key"})
+	}
+
+	if err := testAPIKey(c.UserContext(), key.Provider, key.APIKey, key.BaseURL); err != nil {
+		logger.Warn("stored api key failed test", "err", err, "user_id", userID)
+		return c.JSON(fiber.Map{"ok": false, "error": i18n.T(userLanguage(c, userID), "error."+llm.ErrorKind(err))})
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// DeleteAPIKey handles DELETE /api/user/api-key.
+func DeleteAPIKey(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	if err := keyvault.Delete(c.UserContext(), userID); err != nil {
+		logger.Error("api key delete failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete API key"})
+	}
+	logger.Info("api key deleted", "user_id", userID)
+	return c.JSON(APIKeyStatus{Configured: false})
+}
+
+func testAPIKey(ctx context.Context, provider, apiKey, baseURL string) error {
+	p, err := llm.ForKey(llm.FeatureComment, provider, apiKey, baseURL)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, llm.RequestTimeout)
+	defer cancel()
+	req := llm.UserRequest("", "Rispondi solo con: ok")
+	req.MaxTokens = 5
+	_, err = p.Complete(ctx, req)
+	return err
+}
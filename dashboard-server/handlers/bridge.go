@@ -2,107 +2,337 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"dashboard-server/database"
 	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/scoring"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
 type BridgeTarget struct {
-	TargetName   string `json:"target_name"`
-	TargetSlug   string `json:"target_slug"`
-	BridgeName   string `json:"bridge_name"`
-	BridgeSlug   string `json:"bridge_slug"`
-	SharedPost   string `json:"shared_post_urn"`
-	PostText     string `json:"post_text"`
-	PathStrength int64  `json:"path_strength"`
+	TargetName   string           `json:"target_name"`
+	TargetSlug   string           `json:"target_slug"`
+	BridgeName   string           `json:"bridge_name"`
+	BridgeSlug   string           `json:"bridge_slug"`
+	SharedPost   string           `json:"shared_post_urn"` // co_commenter paths only
+	PostText     string           `json:"post_text"`
+	PathType     string           `json:"path_type"`     // PathKnows or PathCoCommenter
+	PathStrength int64            `json:"path_strength"` // mutual connections, or co-commenter paths through the bridge
+	Score        float64          `json:"score"`         // 0-100, see package scoring
+	Explanation  []scoring.Factor `json:"explanation"`
+
+	TargetSeniority string `json:"target_seniority"`
+	LastSeen        string `json:"last_seen,omitempty"` // latest sign of the path: KNOWS, co-comment or engagement with the bridge
+
+	lastSeen time.Time
+}
+
+// BridgeTargetPage is a page of GET /api/bridge-targets. NextCursor is empty
+// on the last page.
+//
+// Scores are computed here, not in Neo4j, so only the CandidateLimit most
+// recently seen paths matching the filter are scored, sorted and paged.
+// Truncated reports that more paths matched: the pages cover those
+// candidates only, and a narrower filter (role, company, topic) reaches the
+// others.
+type BridgeTargetPage struct {
+	Targets        []BridgeTarget `json:"targets"`
+	NextCursor     string         `json:"next_cursor,omitempty"`
+	Truncated      bool           `json:"truncated"`
+	CandidateLimit int            `json:"candidate_limit"`
 }
 
-// GetBridgeTargets handles GET /api/bridge-targets
-// Core query for the Warm Reach Map: finds people reachable via a 2-hop
-// co-commenter path through posts the user has interacted with.
+// Kinds of bridge path.
+const (
+	// A connection of the user who knows the target (mutual connections
+	// scraped from the target's profile).
+	PathKnows = scoring.PathKnows
+	// Someone who comments where the user engages and where the target comments.
+	PathCoCommenter = scoring.PathCoCommenter
+)
+
+const (
+	// Candidate (target, bridge) pairs scored per request, most recently
+	// seen first (see BridgeTargetPage).
+	maxBridgeCandidates = 1000
+	// Topics the user engages with, compared with the targets' ones.
+	bridgeTopicDays  = 180
+	maxBridgeTopics  = 50
+	maxTargetTopics  = 50
+	defaultBridgeMax = 20
+)
+
+// GetBridgeTargets handles GET /api/bridge-targets?role=&company=&topic=&min_score=&sort=score&cursor=&limit=20
+// Core query for the Warm Reach Map: finds people reachable through a
+// connection who actually knows them (KNOWS) or via a 2-hop co-commenter path
+// through posts the user has interacted with, and ranks them with the Warm
+// Reach score (package scoring), one row per target through its best bridge.
+// People already connected and dismissed targets are left out unless
+// include_connected / include_dismissed are set; see BridgeFilter.
 func GetBridgeTargets(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	start := time.Now()
+	filter, err := parseBridgeFilter(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	conds, params := filter.conditions()
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, "\n\t\t  AND ")
+	}
 
 	ctx := context.Background()
 	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
+	for k, v := range map[string]any{
+		"userId":       userID,
+		"knows":        PathKnows,
+		"coCommenter":  PathCoCommenter,
+		"candidates":   maxBridgeCandidates + 1, // one more, to tell whether the cap was hit
+		"days":         bridgeTopicDays,
+		"topics":       maxBridgeTopics,
+		"targetTopics": maxTargetTopics,
+	} {
+		params[k] = v
+	}
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// 2-hop bridge traversal:
+		rec, err := tx.Run(ctx, `
+		MATCH (:User {id: $userId})-[a:ACTION]->(:Post)-[:HAS_TOPIC]->(t:Topic)
+		WHERE datetime(a.timestamp) >= datetime() - duration({days: $days})
+		RETURN t.name AS name, count(*) AS n
+		ORDER BY n DESC
+		LIMIT $topics
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		var userTopics []string
+		for rec.Next(ctx) {
+			userTopics = append(userTopics, database.RecordString(rec.Record(), "name"))
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		// Real paths: Me → (CONNECTED_TO) → Bridge ─(KNOWS)─ Target, one row
+		// per bridge. KNOWS edges are shared by every user who saw them, so
+		// only those the user observed count (observed_by).
+		//
+		// 2-hop co-commenter traversal, for targets without a real path:
 		// Me → (ACTION on) → Post ← (COMMENTED_ON) ← Bridge Person
 		//                            ↓ (COMMENTED_ON)
 		//                          Post2 ← (COMMENTED_ON) ← Target Person
-		// Target = person I haven't directly reached yet
+		// one row per bridge, with the latest shared post.
+		// Target = person I haven't directly reached yet, then the filter
 		query := `
-		MATCH (me:User {id: $userId})-[:ACTION]->(p:Post)
-		      <-[:COMMENTED_ON]-(bridge:Person)
-		      -[:COMMENTED_ON]->(p2:Post)
-		      <-[:COMMENTED_ON]-(target:Person)
-		WHERE NOT (me)-[:ACTION]->(:Post)<-[:COMMENTED_ON]-(target)
-		  AND target.slug <> bridge.slug
-		  AND target.slug IS NOT NULL
-		  AND bridge.slug IS NOT NULL
+		MATCH (me:User {id: $userId})
+		CALL {
+		  WITH me
+		  MATCH (me)-[:CONNECTED_TO]->(bridge:Person)-[k:KNOWS]-(target:Person)
+		  WHERE $userId IN k.observed_by
+		    AND target <> bridge
+		    AND target.slug IS NOT NULL
+		    AND bridge.slug IS NOT NULL
+		  WITH target, bridge, max(datetime(k.last_seen)) AS last_seen
+		  WITH target, collect({bridge: bridge, last_seen: last_seen}) AS bridges
+		  UNWIND bridges AS b
+		  RETURN target, b.bridge AS bridge, null AS p2, $knows AS path_type, size(bridges) AS paths, b.last_seen AS last_seen
+		  UNION ALL
+		  WITH me
+		  MATCH (me)-[:ACTION]->(p:Post)
+		        <-[:COMMENTED_ON]-(bridge:Person)
+		        -[:COMMENTED_ON]->(p2:Post)
+		        <-[r:COMMENTED_ON]-(target:Person)
+		  WHERE NOT (me)-[:ACTION]->(:Post)<-[:COMMENTED_ON]-(target)
+		    AND NOT EXISTS { (me)-[:CONNECTED_TO]->(b:Person)-[k:KNOWS]-(target) WHERE b <> target AND $userId IN k.observed_by }
+		    AND target.slug <> bridge.slug
+		    AND target.slug IS NOT NULL
+		    AND bridge.slug IS NOT NULL
+		  WITH target, bridge, p2, r
+		  ORDER BY r.first_seen DESC
+		  WITH target, bridge, collect(p2)[0] AS p2, count(*) AS paths, max(datetime(r.first_seen)) AS last_seen
+		  RETURN target, bridge, p2, $coCommenter AS path_type, paths, last_seen
+		}
+		WITH me, target, bridge, p2, path_type, paths, last_seen
+		` + where + `
+		WITH me, target, bridge, p2, path_type, paths, last_seen
+		ORDER BY coalesce(last_seen, datetime({epochMillis: 0})) DESC
+		LIMIT $candidates
+		CALL {
+		  WITH me, bridge
+		  OPTIONAL MATCH (me)-[a:ACTION]->(post:Post)
+		  WHERE (post)-[:AUTHORED_BY]->(bridge) OR (bridge)-[:COMMENTED_ON]->(post)
+		  RETURN max(datetime(a.timestamp)) AS bridge_last
+		}
 		RETURN
-		  target.name   AS target_name,
-		  target.slug   AS target_slug,
-		  bridge.name   AS bridge_name,
-		  bridge.slug   AS bridge_slug,
-		  p2.urn        AS shared_post_urn,
-		  p2.text       AS post_text,
-		  count(*)      AS path_strength
-		ORDER BY path_strength DESC
-		LIMIT 20
+		  target.name      AS target_name,
+		  target.slug      AS target_slug,
+		  target.seniority AS seniority,
+		  head([(me)-[o:OBSERVED]->(target) | o.degree]) AS degree,
+		  bridge.name      AS bridge_name,
+		  bridge.slug      AS bridge_slug,
+		  EXISTS { (me)-[:CONNECTED_TO]->(bridge) } AS bridge_connected,
+		  COUNT { (me)-[:ACTION]->(:Post)-[:AUTHORED_BY]->(bridge) } AS bridge_actions,
+		  COUNT { (me)-[:ACTION]->(:Post)<-[:COMMENTED_ON]-(bridge) } AS bridge_threads,
+		  bridge_last,
+		  p2.urn           AS shared_post_urn,
+		  p2.text          AS post_text,
+		  path_type,
+		  paths,
+		  last_seen,
+		  [(target)<-[:AUTHORED_BY]-(:Post)-[:HAS_TOPIC]->(t:Topic) | t.name][..$targetTopics] +
+		  [(target)-[:COMMENTED_ON]->(:Post)-[:HAS_TOPIC]->(t:Topic) | t.name][..$targetTopics] AS target_topics
 		`
-		records, err := tx.Run(ctx, query, map[string]any{"userId": userID})
+		rec, err = tx.Run(ctx, query, params)
 		if err != nil {
 			return nil, err
 		}
 
-		var targets []BridgeTarget
-		for records.Next(ctx) {
-			r := records.Record()
-			str := func(key string) string {
-				v, _ := r.Get(key)
-				if v == nil {
-					return ""
-				}
-				return fmt.Sprint(v)
+		// Best bridge of each target
+		now := time.Now()
+		best := map[string]BridgeTarget{}
+		rows, truncated := 0, false
+		for rec.Next(ctx) {
+			if rows++; rows > maxBridgeCandidates {
+				truncated = true
+				continue
 			}
-			int64val := func(key string) int64 {
-				v, _ := r.Get(key)
-				if v == nil {
-					return 0
-				}
-				return v.(int64)
+			r := rec.Record()
+			cand := scoring.Candidate{
+				PathType:        database.RecordString(r, "path_type"),
+				Paths:           database.RecordInt(r, "paths"),
+				BridgeActions:   database.RecordInt(r, "bridge_actions"),
+				BridgeThreads:   database.RecordInt(r, "bridge_threads"),
+				TargetSeniority: database.RecordString(r, "seniority"),
+				TargetDegree:    database.RecordString(r, "degree"),
+				UserTopics:      userTopics,
+				TargetTopics:    database.RecordStrings(r, "target_topics"),
+			}
+			connected, _ := r.Get("bridge_connected")
+			cand.BridgeConnected, _ = connected.(bool)
+			cand.LastSeen, _ = recordTime(r, "last_seen")
+			cand.BridgeLast, _ = recordTime(r, "bridge_last")
+
+			score := scoring.Current.Score(cand, now)
+			slug := database.RecordString(r, "target_slug")
+			if prev, ok := best[slug]; score.Total < filter.MinScore || ok && prev.Score >= score.Total {
+				continue
+			}
+			lastSeen := cand.LastSeen
+			if cand.BridgeLast.After(lastSeen) {
+				lastSeen = cand.BridgeLast
+			}
+			best[slug] = BridgeTarget{
+				TargetName:   database.RecordString(r, "target_name"),
+				TargetSlug:   slug,
+				BridgeName:   database.RecordString(r, "bridge_name"),
+				BridgeSlug:   database.RecordString(r, "bridge_slug"),
+				SharedPost:   database.RecordString(r, "shared_post_urn"),
+				PostText:     database.RecordString(r, "post_text"),
+				PathType:     cand.PathType,
+				PathStrength: cand.Paths,
+				Score:        score.Total,
+				Explanation:  score.Explanation,
+
+				TargetSeniority: cand.TargetSeniority,
+				LastSeen:        formatTime(lastSeen),
+				lastSeen:        lastSeen,
 			}
-			targets = append(targets, BridgeTarget{
-				TargetName:   str("target_name"),
-				TargetSlug:   str("target_slug"),
-				BridgeName:   str("bridge_name"),
-				BridgeSlug:   str("bridge_slug"),
-				SharedPost:   str("shared_post_urn"),
-				PostText:     str("post_text"),
-				PathStrength: int64val("path_strength"),
-			})
 		}
-		if targets == nil {
-			targets = []BridgeTarget{}
+		if err := rec.Err(); err != nil {
+			return nil, err
 		}
-		return targets, nil
+
+		// Sort, then the page after the cursor
+		targets := make([]BridgeTarget, 0, len(best))
+		for _, t := range best {
+			if filter.Cursor == nil || filter.Cursor.before(bridgeSortKey(filter.Sort, t)) {
+				targets = append(targets, t)
+			}
+		}
+		sort.Slice(targets, func(i, j int) bool {
+			return bridgeSortKey(filter.Sort, targets[i]).before(bridgeSortKey(filter.Sort, targets[j]))
+		})
+		page := BridgeTargetPage{Targets: targets, Truncated: truncated, CandidateLimit: maxBridgeCandidates}
+		if len(targets) > filter.Limit {
+			page.Targets = targets[:filter.Limit]
+			last := page.Targets[filter.Limit-1]
+			page.NextCursor = bridgeCursor{Sort: filter.Sort, bridgeKey: bridgeSortKey(filter.Sort, last)}.encode()
+		}
+		return page, nil
 	})
 
 	if err != nil {
 		logger.Error("bridge-targets query failed", "err", err, "user_id", userID)
-		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed: " + err.Error()})
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
 	}
 
-	targets := result.([]BridgeTarget)
-	logger.Info("bridge-targets served", "user_id", userID, "count", len(targets), "duration_ms", time.Since(start).Milliseconds())
+	page := result.(BridgeTargetPage)
+	logger.Info("bridge-targets served", "user_id", userID, "count", len(page.Targets), "sort", filter.Sort, "truncated", page.Truncated, "duration_ms", time.Since(start).Milliseconds())
 	return c.JSON(result)
 }
+
+// DismissBridgeTarget handles POST /api/bridge-targets/:slug/dismiss
+// Hides a target from the Warm Reach Map (unless include_dismissed is set).
+func DismissBridgeTarget(c *fiber.Ctx) error {
+	return setBridgeTargetDismissed(c, true)
+}
+
+// RestoreBridgeTarget handles DELETE /api/bridge-targets/:slug/dismiss
+func RestoreBridgeTarget(c *fiber.Ctx) error {
+	return setBridgeTargetDismissed(c, false)
+}
+
+func setBridgeTargetDismissed(c *fiber.Ctx, dismissed bool) error {
+	userID := middleware.UserID(c)
+	slug := c.Params("slug")
+
+	query := `
+		MATCH (u:User {id: $userId}), (p:Person {slug: $slug})
+		MERGE (u)-[d:DISMISSED]->(p)
+		  ON CREATE SET d.at = toString(datetime())
+		RETURN p.slug AS slug
+	`
+	if !dismissed {
+		query = `
+		MATCH (:User {id: $userId})-[d:DISMISSED]->(p:Person {slug: $slug})
+		DELETE d
+		RETURN p.slug AS slug
+		`
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	found, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID, "slug": slug})
+		if err != nil {
+			return false, err
+		}
+		return rec.Next(ctx), rec.Err()
+	})
+	if err != nil {
+		logger.Error("bridge target dismiss failed", "err", err, "user_id", userID, "slug", slug, "dismissed", dismissed)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	if !found.(bool) && dismissed {
+		return c.Status(404).JSON(fiber.Map{"error": "Person not found"})
+	}
+	return c.JSON(fiber.Map{"slug": slug, "dismissed": dismissed})
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
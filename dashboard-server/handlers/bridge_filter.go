@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BridgeFilter is the query of GET /api/bridge-targets. Role, Company and
+// Topic narrow the candidates in Cypher (see conditions), the score and the
+// page are applied to the scored targets.
+type BridgeFilter struct {
+	Role             string  // keyword in the target's headline or job title
+	Company          string  // slug, or part of the name, of the target's company
+	Topic            string  // topic of the target's posts or comments
+	MinScore         float64 // 0-100
+	IncludeConnected bool    // also people the user is already connected to
+	IncludeDismissed bool    // also the targets the user dismissed
+	Sort             string  // one of bridgeSorts
+	Cursor           *bridgeCursor
+	Limit            int
+}
+
+// Sorts of the targets. Ties go by score (by recency when sorting by
+// score), then by name.
+const (
+	BridgeSortScore     = "score"     // best first
+	BridgeSortRecency   = "recency"   // latest sign of the path first
+	BridgeSortSeniority = "seniority" // most senior first
+	BridgeSortName      = "name"      // alphabetical
+)
+
+var bridgeSorts = []string{BridgeSortScore, BridgeSortRecency, BridgeSortSeniority, BridgeSortName}
+
+const maxBridgeFilterText = 100
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// parseBridgeFilter reads the filter from the query string:
+// role, company, topic, min_score, include_connected, include_dismissed,
+// sort, cursor and limit.
+func parseBridgeFilter(c *fiber.Ctx) (BridgeFilter, error) {
+	f := BridgeFilter{
+		Role:             truncateRunes(strings.TrimSpace(c.Query("role")), maxBridgeFilterText),
+		Company:          truncateRunes(strings.TrimSpace(c.Query("company")), maxBridgeFilterText),
+		Topic:            truncateRunes(strings.TrimSpace(c.Query("topic")), maxBridgeFilterText),
+		IncludeConnected: c.QueryBool("include_connected", false),
+		IncludeDismissed: c.QueryBool("include_dismissed", false),
+		Sort:             c.Query("sort", BridgeSortScore),
+		Limit:            c.QueryInt("limit", defaultBridgeMax),
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = defaultBridgeMax
+	}
+	if v := c.Query("min_score"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil || score < 0 || score > 100 {
+			return f, errors.New("min_score must be a number between 0 and 100")
+		}
+		f.MinScore = score
+	}
+	if !slices.Contains(bridgeSorts, f.Sort) {
+		return f, errors.New("sort must be one of " + strings.Join(bridgeSorts, ", "))
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeBridgeCursor(v)
+		if err != nil || cursor.Sort != f.Sort {
+			return f, errInvalidCursor
+		}
+		f.Cursor = cursor
+	}
+	return f, nil
+}
+
+// conditions returns the filter's conditions on the candidate (me, target)
+// rows, to be ANDed in a WHERE, with their parameters. Values only ever
+// travel as parameters, never in the query text.
+func (f BridgeFilter) conditions() ([]string, map[string]any) {
+	var conds []string
+	params := map[string]any{}
+	if !f.IncludeConnected {
+		conds = append(conds, `NOT (me)-[:CONNECTED_TO]->(target)`)
+	}
+	if !f.IncludeDismissed {
+		conds = append(conds, `NOT (me)-[:DISMISSED]->(target)`)
+	}
+	if f.Role != "" {
+		conds = append(conds, `(toLower(coalesce(target.headline, '')) CONTAINS $role
+		   OR toLower(coalesce(target.job_title, '')) CONTAINS $role)`)
+		params["role"] = strings.ToLower(f.Role)
+	}
+	if f.Company != "" {
+		conds = append(conds, `EXISTS {
+		     MATCH (target)-[:WORKS_AT]->(co:Company)
+		     WHERE co.slug = $company OR toLower(co.name) CONTAINS $companyName
+		   }`)
+		params["company"] = f.Company
+		params["companyName"] = strings.ToLower(f.Company)
+	}
+	if f.Topic != "" {
+		conds = append(conds, `(EXISTS { MATCH (target)<-[:AUTHORED_BY]-(:Post)-[:HAS_TOPIC]->(t:Topic) WHERE toLower(t.name) = $topic }
+		   OR EXISTS { MATCH (target)-[:COMMENTED_ON]->(:Post)-[:HAS_TOPIC]->(t:Topic) WHERE toLower(t.name) = $topic })`)
+		params["topic"] = strings.ToLower(f.Topic)
+	}
+	return conds, params
+}
+
+// bridgeCursor is the position after the last target of a page: the sort
+// key of that target. Scores are computed at each request, so a target whose
+// score moved across the cursor in the meantime can be skipped or repeated,
+// like rows inserted while paging with an offset.
+type bridgeCursor struct {
+	Sort string `json:"sort"`
+	bridgeKey
+}
+
+type bridgeKey struct {
+	Primary   float64 `json:"p"`
+	Secondary float64 `json:"s"`
+	Name      string  `json:"n"`
+	Slug      string  `json:"id"`
+}
+
+var seniorityRanks = map[string]float64{
+	"executive": 6, "lead": 5, "senior": 4, "mid": 3, "junior": 2, "intern": 1,
+}
+
+// bridgeSortKey is the key of t for sort: higher keys first, then names and
+// slugs in order.
+func bridgeSortKey(sort string, t BridgeTarget) bridgeKey {
+	key := bridgeKey{Name: strings.ToLower(t.TargetName), Slug: t.TargetSlug}
+	var seen float64
+	if !t.lastSeen.IsZero() {
+		seen = float64(t.lastSeen.Unix())
+	}
+	switch sort {
+	case BridgeSortScore:
+		key.Primary, key.Secondary = t.Score, seen
+	case BridgeSortRecency:
+		key.Primary, key.Secondary = seen, t.Score
+	case BridgeSortSeniority:
+		key.Primary, key.Secondary = seniorityRanks[t.TargetSeniority], t.Score
+	}
+	return key
+}
+
+// before reports whether k sorts before other.
+func (k bridgeKey) before(other bridgeKey) bool {
+	if k.Primary != other.Primary {
+		return k.Primary > other.Primary
+	}
+	if k.Secondary != other.Secondary {
+		return k.Secondary > other.Secondary
+	}
+	if k.Name != other.Name {
+		return k.Name < other.Name
+	}
+	return k.Slug < other.Slug
+}
+
+func (c bridgeCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBridgeCursor(s string) (*bridgeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c bridgeCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
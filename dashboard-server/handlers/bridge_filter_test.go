@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBridgeFilterConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     BridgeFilter
+		wantConds  []string // substrings, one per condition, in order
+		wantParams map[string]any
+	}{
+		{
+			name:       "defaults exclude connected and dismissed",
+			filter:     BridgeFilter{},
+			wantConds:  []string{"NOT (me)-[:CONNECTED_TO]->(target)", "NOT (me)-[:DISMISSED]->(target)"},
+			wantParams: map[string]any{},
+		},
+		{
+			name:       "include both",
+			filter:     BridgeFilter{IncludeConnected: true, IncludeDismissed: true},
+			wantConds:  nil,
+			wantParams: map[string]any{},
+		},
+		{
+			name:       "role",
+			filter:     BridgeFilter{IncludeConnected: true, IncludeDismissed: true, Role: "Head of Data"},
+			wantConds:  []string{"CONTAINS $role"},
+			wantParams: map[string]any{"role": "head of data"},
+		},
+		{
+			name:      "company",
+			filter:    BridgeFilter{IncludeConnected: true, IncludeDismissed: true, Company: "Acme"},
+			wantConds: []string{"co.slug = $company OR toLower(co.name) CONTAINS $companyName"},
+			wantParams: map[string]any{
+				"company":     "Acme",
+				"companyName": "acme",
+			},
+		},
+		{
+			name:       "topic",
+			filter:     BridgeFilter{IncludeConnected: true, IncludeDismissed: true, Topic: "AI"},
+			wantConds:  []string{"toLower(t.name) = $topic"},
+			wantParams: map[string]any{"topic": "ai"},
+		},
+		{
+			name:   "all",
+			filter: BridgeFilter{Role: "cto", Company: "acme", Topic: "go"},
+			wantConds: []string{
+				"NOT (me)-[:CONNECTED_TO]->(target)", "NOT (me)-[:DISMISSED]->(target)",
+				"$role", "$company", "$topic",
+			},
+			wantParams: map[string]any{"role": "cto", "company": "acme", "companyName": "acme", "topic": "go"},
+		},
+		{
+			name:   "score, sort and page stay out of Cypher",
+			filter: BridgeFilter{MinScore: 50, Sort: BridgeSortName, Limit: 5, Cursor: &bridgeCursor{Sort: BridgeSortName}},
+			wantConds: []string{
+				"NOT (me)-[:CONNECTED_TO]->(target)", "NOT (me)-[:DISMISSED]->(target)",
+			},
+			wantParams: map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, params := tt.filter.conditions()
+			if len(conds) != len(tt.wantConds) {
+				t.Fatalf("conditions = %q, want %d", conds, len(tt.wantConds))
+			}
+			for i, want := range tt.wantConds {
+				if !strings.Contains(conds[i], want) {
+					t.Errorf("condition %d = %q, want it to contain %q", i, conds[i], want)
+				}
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestBridgeFilterConditionsKeepValuesOutOfQuery(t *testing.T) {
+	hostile := []string{
+		"x') RETURN 1 //",
+		"' OR 1=1 OR '",
+		"}) DETACH DELETE target //",
+		"$userId",
+		"`target`",
+	}
+	for _, v := range hostile {
+		conds, params := BridgeFilter{Role: v, Company: v, Topic: v}.conditions()
+		query := strings.Join(conds, " AND ")
+		if strings.Contains(query, v) || strings.Contains(query, strings.ToLower(v)) {
+			t.Errorf("value %q ended up in the query text: %s", v, query)
+		}
+		for _, key := range []string{"role", "companyName", "topic"} {
+			if params[key] != strings.ToLower(v) {
+				t.Errorf("params[%s] = %v, want %q", key, params[key], strings.ToLower(v))
+			}
+		}
+	}
+}
+
+// parseQuery runs parseBridgeFilter on a request with query q.
+func parseQuery(t *testing.T, q url.Values) (BridgeFilter, error) {
+	t.Helper()
+	var (
+		filter BridgeFilter
+		err    error
+	)
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		filter, err = parseBridgeFilter(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+q.Encode(), nil)); testErr != nil {
+		t.Fatalf("app.Test: %v", testErr)
+	}
+	return filter, err
+}
+
+func TestParseBridgeFilter(t *testing.T) {
+	validCursor := bridgeCursor{Sort: BridgeSortRecency, bridgeKey: bridgeKey{Primary: 1, Slug: "a"}}.encode()
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		want    BridgeFilter
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: url.Values{},
+			want:  BridgeFilter{Sort: BridgeSortScore, Limit: defaultBridgeMax},
+		},
+		{
+			name: "all fields",
+			query: url.Values{
+				"role": {"  CTO "}, "company": {"acme"}, "topic": {"AI"}, "min_score": {"42.5"},
+				"include_connected": {"true"}, "include_dismissed": {"1"}, "sort": {"seniority"}, "limit": {"50"},
+			},
+			want: BridgeFilter{
+				Role: "CTO", Company: "acme", Topic: "AI", MinScore: 42.5,
+				IncludeConnected: true, IncludeDismissed: true, Sort: BridgeSortSeniority, Limit: 50,
+			},
+		},
+		{
+			name:  "text is capped",
+			query: url.Values{"role": {strings.Repeat("é", 150)}},
+			want:  BridgeFilter{Role: strings.Repeat("é", maxBridgeFilterText), Sort: BridgeSortScore, Limit: defaultBridgeMax},
+		},
+		{
+			name:  "limit out of range falls back",
+			query: url.Values{"limit": {"1000"}},
+			want:  BridgeFilter{Sort: BridgeSortScore, Limit: defaultBridgeMax},
+		},
+		{
+			name:  "cursor",
+			query: url.Values{"sort": {"recency"}, "cursor": {validCursor}},
+			want: BridgeFilter{Sort: BridgeSortRecency, Limit: defaultBridgeMax,
+				Cursor: &bridgeCursor{Sort: BridgeSortRecency, bridgeKey: bridgeKey{Primary: 1, Slug: "a"}}},
+		},
+		{name: "unknown sort", query: url.Values{"sort": {"random"}}, wantErr: true},
+		{name: "min_score not a number", query: url.Values{"min_score": {"high"}}, wantErr: true},
+		{name: "min_score negative", query: url.Values{"min_score": {"-1"}}, wantErr: true},
+		{name: "min_score over 100", query: url.Values{"min_score": {"101"}}, wantErr: true},
+		{name: "cursor garbage", query: url.Values{"cursor": {"%%%"}}, wantErr: true},
+		{name: "cursor not json", query: url.Values{"cursor": {"bm90IGpzb24"}}, wantErr: true},
+		{name: "cursor of another sort", query: url.Values{"sort": {"score"}, "cursor": {validCursor}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuery(t, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBridgeCursorRoundTrip(t *testing.T) {
+	cursors := []bridgeCursor{
+		{Sort: BridgeSortScore, bridgeKey: bridgeKey{Primary: 87.3, Secondary: 1760000000, Name: "ada", Slug: "ada-l"}},
+		{Sort: BridgeSortName, bridgeKey: bridgeKey{Name: "zoë ñ 東京", Slug: "x/y?z=1&w"}},
+		{Sort: BridgeSortRecency},
+	}
+	for _, c := range cursors {
+		encoded := c.encode()
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("cursor %q is not URL-safe", encoded)
+		}
+		decoded, err := decodeBridgeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decode(%q): %v", encoded, err)
+		}
+		if *decoded != c {
+			t.Errorf("round trip = %+v, want %+v", *decoded, c)
+		}
+	}
+}
+
+func TestBridgeKeyOrder(t *testing.T) {
+	now := time.Now()
+	targets := []BridgeTarget{
+		{TargetName: "Carla", TargetSlug: "carla", Score: 50, TargetSeniority: "lead", lastSeen: now.Add(-time.Hour)},
+		{TargetName: "Bruno", TargetSlug: "bruno", Score: 80, TargetSeniority: "junior", lastSeen: now.Add(-48 * time.Hour)},
+		{TargetName: "anna", TargetSlug: "anna-2", Score: 50, TargetSeniority: "executive"},
+		{TargetName: "Anna", TargetSlug: "anna-1", Score: 50, TargetSeniority: "lead", lastSeen: now.Add(-time.Hour)},
+		{TargetName: "Dario", TargetSlug: "dario", Score: 50, TargetSeniority: "unspecified", lastSeen: now.Add(-time.Hour)},
+	}
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		// Score, then most recent, then name and slug
+		{BridgeSortScore, []string{"bruno", "anna-1", "carla", "dario", "anna-2"}},
+		// Most recent, then score: never seen last
+		{BridgeSortRecency, []string{"anna-1", "carla", "dario", "bruno", "anna-2"}},
+		// Seniority, then score, then name
+		{BridgeSortSeniority, []string{"anna-2", "anna-1", "carla", "bruno", "dario"}},
+		// Case-insensitive name, then slug
+		{BridgeSortName, []string{"anna-1", "anna-2", "bruno", "carla", "dario"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			sorted := append([]BridgeTarget(nil), targets...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return bridgeSortKey(tt.sort, sorted[i]).before(bridgeSortKey(tt.sort, sorted[j]))
+			})
+			var got []string
+			for _, target := range sorted {
+				got = append(got, target.TargetSlug)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+
+			// The cursor of each position lets exactly the following targets through
+			for i, target := range sorted {
+				cursor := bridgeCursor{Sort: tt.sort, bridgeKey: bridgeSortKey(tt.sort, target)}
+				for j, other := range sorted {
+					if after := cursor.before(bridgeSortKey(tt.sort, other)); after != (j > i) {
+						t.Errorf("cursor at %s: %s passes = %v, want %v", target.TargetSlug, other.TargetSlug, after, j > i)
+					}
+				}
+			}
+		})
+	}
+}
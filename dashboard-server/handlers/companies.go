@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+
+	"dashboard-server/database"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Companies come from (:Person)-[:WORKS_AT]->(:Company), parsed from the
+// headlines by event-service (and the enrichment fallback), and from the
+// companies mentioned in posts.
+
+type CompanyItem struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Contacts int64  `json:"contacts"`  // 1st degree connections working there
+	Observed int64  `json:"observed"`  // people working there seen in the feed, not connected
+	Activity int64  `json:"activity"`  // user actions on posts by people working there or mentioning it
+	OpenJobs int64  `json:"open_jobs"` // announced by the user's network, as in GET /api/jobs
+	Score    int64  `json:"score"`
+}
+
+type CompanyPerson struct {
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Title     string `json:"title"`
+	Seniority string `json:"seniority"`
+	Connected bool   `json:"connected"`
+	Degree    string `json:"degree"`
+}
+
+type CompanyDetail struct {
+	CompanyItem
+	Inferred bool            `json:"inferred"` // created from a headline or a job post, not seen on LinkedIn
+	People   []CompanyPerson `json:"people"`
+	Jobs     []JobItem       `json:"jobs"`
+}
+
+// Weights of the power account score: a contact is worth more than an
+// interaction, which is worth more than someone only seen in the feed.
+const (
+	companyScoreContact  = 3
+	companyScoreActivity = 2
+	companyScoreObserved = 1
+)
+
+var companySorts = map[string]string{
+	"score":    "score",
+	"contacts": "contacts",
+	"activity": "activity",
+	"jobs":     "open_jobs",
+}
+
+// companyStatsQuery computes CompanyItem for the companies bound to c (after
+// the caller's MATCH), for the user u, over the last $days of activity.
+const companyStatsQuery = `
+	WITH u, c,
+	     COUNT { (u)-[:CONNECTED_TO]->(:Person)-[:WORKS_AT]->(c) } AS contacts,
+	     COUNT { MATCH (u)-[:OBSERVED]->(p:Person)-[:WORKS_AT]->(c) WHERE NOT (u)-[:CONNECTED_TO]->(p) } AS observed,
+	     COUNT { MATCH (u)-[a:ACTION]->(post:Post)
+	             WHERE datetime(a.timestamp) >= datetime() - duration({days: $days})
+	               AND ((post)-[:AUTHORED_BY]->(:Person)-[:WORKS_AT]->(c) OR (post)-[:MENTIONS]->(c)) } AS activity,
+	     COUNT { MATCH (c)-[:HIRING]->(:Job)<-[:ANNOUNCED_IN]-(:Post)-[:AUTHORED_BY]->(a:Person)
+	             WHERE (u)-[:CONNECTED_TO|OBSERVED]->(a) } AS open_jobs
+	RETURN c.name AS name, c.slug AS slug, c.inferred AS inferred, contacts, observed, activity, open_jobs,
+	       contacts * $wContact + activity * $wActivity + observed * $wObserved AS score
+`
+
+// GetCompanies handles GET /api/companies?sort=score&days=90&limit=20
+// The user's power accounts: the companies where they have the most contacts
+// or activity.
+func GetCompanies(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	sort, ok := companySorts[c.Query("sort", "score")]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "sort must be one of score, contacts, activity, jobs"})
+	}
+	days := c.QueryInt("days", 90)
+	if days < 1 || days > 365 {
+		days = 90
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// Candidates: companies of the people in the user's network, and the
+		// ones mentioned in posts the user acted on
+		query := `
+		MATCH (u:User {id: $userId})
+		CALL {
+		  WITH u
+		  MATCH (u)-[:CONNECTED_TO|OBSERVED]->(:Person)-[:WORKS_AT]->(c:Company)
+		  RETURN c
+		  UNION
+		  WITH u
+		  MATCH (u)-[a:ACTION]->(:Post)-[:MENTIONS]->(c:Company)
+		  WHERE datetime(a.timestamp) >= datetime() - duration({days: $days})
+		  RETURN c
+		}
+		` + companyStatsQuery + `
+		ORDER BY ` + sort + ` DESC, contacts DESC, name
+		LIMIT $limit
+		`
+		rec, err := tx.Run(ctx, query, companyParams(userID, days, map[string]any{"limit": limit}))
+		if err != nil {
+			return nil, err
+		}
+
+		items := []CompanyItem{}
+		for rec.Next(ctx) {
+			items = append(items, companyItem(rec.Record()))
+		}
+		return items, rec.Err()
+	})
+
+	if err != nil {
+		logger.Error("companies query failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+
+	return c.JSON(result)
+}
+
+// GetCompany handles GET /api/companies/:slug?days=90
+// A company seen from the user's network: the people they know there and
+// the open roles announced by their network.
+func GetCompany(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	slug := c.Params("slug")
+	days := c.QueryInt("days", 90)
+	if days < 1 || days > 365 {
+		days = 90
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		params := companyParams(userID, days, map[string]any{"slug": slug})
+		rec, err := tx.Run(ctx, `
+		MATCH (u:User {id: $userId}), (c:Company {slug: $slug})
+		`+companyStatsQuery, params)
+		if err != nil {
+			return nil, err
+		}
+		if !rec.Next(ctx) {
+			return nil, rec.Err()
+		}
+		r := rec.Record()
+		inferred, _ := r.Get("inferred")
+		detail := &CompanyDetail{CompanyItem: companyItem(r), People: []CompanyPerson{}, Jobs: []JobItem{}}
+		detail.Inferred, _ = inferred.(bool)
+
+		// Only people the user knows: connections first, then by seniority
+		rec, err = tx.Run(ctx, `
+		MATCH (u:User {id: $userId})-[:OBSERVED|CONNECTED_TO]->(p:Person)-[w:WORKS_AT]->(:Company {slug: $slug})
+		WITH DISTINCT u, p, w
+		RETURN p.name AS name, p.slug AS slug, coalesce(w.title, p.job_title) AS title, p.seniority AS seniority,
+		       EXISTS { (u)-[:CONNECTED_TO]->(p) } AS connected, head([(u)-[o:OBSERVED]->(p) | o.degree]) AS degree
+		ORDER BY connected DESC,
+		         CASE seniority WHEN 'executive' THEN 0 WHEN 'lead' THEN 1 WHEN 'senior' THEN 2 ELSE 3 END, name
+		LIMIT 100
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			connected, _ := r.Get("connected")
+			person := CompanyPerson{
+				Name:      database.RecordString(r, "name"),
+				Slug:      database.RecordString(r, "slug"),
+				Title:     database.RecordString(r, "title"),
+				Seniority: database.RecordString(r, "seniority"),
+				Degree:    database.RecordString(r, "degree"),
+			}
+			person.Connected, _ = connected.(bool)
+			detail.People = append(detail.People, person)
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		// Same visibility as GET /api/jobs: roles announced by the user's network
+		rec, err = tx.Run(ctx, `
+		MATCH (u:User {id: $userId})
+		MATCH (c:Company {slug: $slug})-[:HIRING]->(j:Job)<-[:ANNOUNCED_IN]-(p:Post)-[:AUTHORED_BY]->(a:Person)
+		WHERE (u)-[:CONNECTED_TO]->(a) OR (u)-[:OBSERVED]->(a)
+		RETURN j.id AS id, j.role AS role, j.seniority AS seniority, j.location AS location, j.remote AS remote,
+		       coalesce(c.name, j.company_name) AS company_name, c.slug AS company_slug,
+		       p.urn AS post_urn, a.name AS author_name, a.slug AS author_slug,
+		       EXISTS { (u)-[:CONNECTED_TO]->(a) } AS connected,
+		       head([(u)-[o:OBSERVED]->(a) | o.degree]) AS degree, j.announced_at AS announced_at
+		ORDER BY announced_at DESC
+		LIMIT 50
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			detail.Jobs = append(detail.Jobs, jobItem(rec.Record()))
+		}
+		return detail, rec.Err()
+	})
+
+	if err != nil {
+		logger.Error("company query failed", "err", err, "user_id", userID, "slug", slug)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	if result == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Company not found"})
+	}
+
+	return c.JSON(result)
+}
+
+func companyParams(userID int64, days int, extra map[string]any) map[string]any {
+	params := map[string]any{
+		"userId":    userID,
+		"days":      days,
+		"wContact":  companyScoreContact,
+		"wActivity": companyScoreActivity,
+		"wObserved": companyScoreObserved,
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	return params
+}
+
+func companyItem(r *neo4j.Record) CompanyItem {
+	item := CompanyItem{Name: database.RecordString(r, "name"), Slug: database.RecordString(r, "slug")}
+	for key, dst := range map[string]*int64{
+		"contacts": &item.Contacts, "observed": &item.Observed, "activity": &item.Activity,
+		"open_jobs": &item.OpenJobs, "score": &item.Score,
+	} {
+		v, _ := r.Get(key)
+		*dst, _ = v.(int64)
+	}
+	return item
+}
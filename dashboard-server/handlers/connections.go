@@ -7,6 +7,7 @@ import (
 
 	"dashboard-server/database"
 	"dashboard-server/logger"
+	"dashboard-server/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -26,7 +27,7 @@ type InsightItem struct {
 
 // GetConnectionsStats handles GET /api/connections/stats
 func GetConnectionsStats(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	ctx := context.Background()
 	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
@@ -57,7 +58,7 @@ func GetConnectionsStats(c *fiber.Ctx) error {
 
 // GetConnectionsList handles GET /api/connections/list
 func GetConnectionsList(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
@@ -108,22 +109,57 @@ func GetConnectionsList(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
-// GetNetworkInsights handles GET /api/connections/insights
+// insightGroups are the ways GetNetworkInsights can break the connections
+// down, all precomputed from the parsed headlines: role category of the
+// built-in taxonomy, the user's own categories (see RoleCategory), seniority,
+// job title and employer. Each connection counts in one bucket at most.
+var insightGroups = map[string]string{
+	"category": `
+		MATCH (u:User {id: $userId})-[:CONNECTED_TO]->(p:Person)
+		WHERE p.role_category IS NOT NULL
+		RETURN p.role_category AS role, count(*) AS count
+		ORDER BY count DESC
+	`,
+	"custom": `
+		MATCH (u:User {id: $userId})-[r:CONNECTED_TO]->(:Person)
+		WHERE r.custom_category IS NOT NULL AND r.custom_category_version = u.role_categories_version
+		RETURN r.custom_category AS role, count(*) AS count
+		ORDER BY count DESC
+	`,
+	"seniority": `
+		MATCH (u:User {id: $userId})-[:CONNECTED_TO]->(p:Person)
+		WHERE p.seniority IS NOT NULL AND p.seniority <> 'unspecified'
+		RETURN p.seniority AS role, count(*) AS count
+		ORDER BY count DESC
+	`,
+	"title": `
+		MATCH (u:User {id: $userId})-[:CONNECTED_TO]->(p:Person)
+		WHERE p.job_title IS NOT NULL
+		RETURN p.job_title AS role, count(*) AS count
+		ORDER BY count DESC
+		LIMIT 20
+	`,
+	"company": `
+		MATCH (u:User {id: $userId})-[:CONNECTED_TO]->(p:Person)-[:WORKS_AT]->(c:Company)
+		RETURN coalesce(c.name, c.slug) AS role, count(DISTINCT p) AS count
+		ORDER BY count DESC
+		LIMIT 20
+	`,
+}
+
+// GetNetworkInsights handles GET /api/connections/insights?by=category|custom|seniority|title|company
 func GetNetworkInsights(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
+	by := c.Query("by", "category")
+	query, ok := insightGroups[by]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "by must be one of category, custom, seniority, title, company"})
+	}
 	ctx := context.Background()
 	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-		MATCH (u:User {id: $userId})-[:CONNECTED_TO]->(p:Person)
-		UNWIND ["CEO", "Founder", "CTO", "CPO", "Product", "Head of", "Manager", "Engineer", "Developer", "AI", "Startup", "Recruiter", "Investor"] AS role
-		WITH p, role
-		WHERE toLower(p.headline) CONTAINS toLower(role)
-		RETURN role, count(*) AS count
-		ORDER BY count DESC
-		`
 		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID})
 		if err != nil {
 			return nil, err
@@ -158,7 +194,7 @@ type OverlapItem struct {
 
 // GetNetworkOverlap handles GET /api/connections/overlap
 func GetNetworkOverlap(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	ctx := context.Background()
 	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
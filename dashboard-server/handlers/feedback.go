@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Generation outcomes, as reported by the plugin.
+const (
+	OutcomeAccepted  = "accepted"  // posted as generated
+	OutcomeEdited    = "edited"    // posted after changes
+	OutcomeDiscarded = "discarded" // never posted
+)
+
+// maxFinalText bounds final_text: the edit distance is quadratic in its length.
+const maxFinalText = 10000
+
+type OutcomeRequest struct {
+	Outcome   string `json:"outcome,omitempty"` // optional when final_text is sent
+	FinalText string `json:"final_text,omitempty"`
+	PostUrn   string `json:"postUrn,omitempty"` // links the generation to its post if it was not already
+}
+
+type OutcomeResponse struct {
+	GenerationID string  `json:"generation_id"`
+	Outcome      string  `json:"outcome"`
+	EditDistance int     `json:"edit_distance"`
+	EditRatio    float64 `json:"edit_ratio"`
+}
+
+// ReportOutcome handles POST /api/ai/generations/:id/outcome.
+// With final_text and no outcome, the outcome is derived: identical text is
+// accepted, anything else edited.
+func ReportOutcome(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	generationID := c.Params("id")
+
+	var req OutcomeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.invalid_body")})
+	}
+	req.FinalText = strings.TrimSpace(req.FinalText)
+	if utf8.RuneCountInString(req.FinalText) > maxFinalText {
+		return c.Status(400).JSON(fiber.Map{"error": "final_text is too long"})
+	}
+	switch req.Outcome {
+	case OutcomeAccepted, OutcomeEdited, OutcomeDiscarded:
+	case "":
+		if req.FinalText == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "outcome or final_text is required"})
+		}
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "outcome must be accepted, edited or discarded"})
+	}
+
+	generated, found, err := generationText(userID, generationID)
+	if err != nil {
+		logger.Error("generation lookup failed", "err", err, "user_id", userID, "generation_id", generationID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	if !found {
+		return c.Status(404).JSON(fiber.Map{"error": "Generation not found"})
+	}
+
+	resp := OutcomeResponse{GenerationID: generationID, Outcome: req.Outcome}
+	if req.Outcome != OutcomeDiscarded {
+		if req.FinalText == "" {
+			req.FinalText = generated
+		}
+		resp.EditDistance = editDistance(generated, req.FinalText)
+		resp.EditRatio = editRatio(resp.EditDistance, generated, req.FinalText)
+		if resp.Outcome == "" {
+			resp.Outcome = OutcomeAccepted
+			if resp.EditDistance > 0 {
+				resp.Outcome = OutcomeEdited
+			}
+		}
+	}
+
+	if err := saveOutcome(userID, generationID, req, resp); err != nil {
+		logger.Error("outcome save failed", "err", err, "user_id", userID, "generation_id", generationID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	return c.JSON(resp)
+}
+
+// generationText returns the generated text of one of the user's generations.
+func generationText(userID int64, generationID string) (string, bool, error) {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (:User {id: $userId})-[:GENERATED]->(g:Generation {id: $id})
+			RETURN coalesce(g.text, '') AS text
+		`, map[string]any{"userId": userID, "id": generationID})
+		if err != nil {
+			return nil, err
+		}
+		if rec.Next(ctx) {
+			text := database.RecordString(rec.Record(), "text")
+			return &text, nil
+		}
+		return (*string)(nil), rec.Err()
+	})
+	if err != nil {
+		return "", false, err
+	}
+	text := result.(*string)
+	if text == nil {
+		return "", false, nil
+	}
+	return *text, true, nil
+}
+
+func saveOutcome(userID int64, generationID string, req OutcomeRequest, resp OutcomeResponse) error {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (:User {id: $userId})-[:GENERATED]->(g:Generation {id: $id})
+		SET g.outcome = $outcome, g.outcome_at = datetime(),
+		    g.final_text = $finalText, g.edit_distance = $editDistance, g.edit_ratio = $editRatio
+		WITH g
+		WHERE $postUrn <> '' AND NOT (g)-[:FOR_POST]->(:Post)
+		MATCH (p:Post {urn: $postUrn})
+		MERGE (g)-[:FOR_POST]->(p)
+		`
+		params := map[string]any{
+			"userId":       userID,
+			"id":           generationID,
+			"outcome":      resp.Outcome,
+			"finalText":    nil,
+			"editDistance": nil,
+			"editRatio":    nil,
+			"postUrn":      req.PostUrn,
+		}
+		if resp.Outcome != OutcomeDiscarded {
+			params["finalText"] = req.FinalText
+			params["editDistance"] = resp.EditDistance
+			params["editRatio"] = resp.EditRatio
+		}
+		_, err := tx.Run(ctx, query, params)
+		return nil, err
+	})
+	return err
+}
+
+// editDistance is the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// editRatio normalizes the distance by the longer text: 0 unchanged, 1 rewritten.
+func editRatio(distance int, a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	return float64(distance) / float64(longest)
+}
+
+type OutcomeStats struct {
+	Key            string   `json:"key"` // prompt version or purpose
+	Generated      int64    `json:"generated"`
+	Reported       int64    `json:"reported"`
+	Accepted       int64    `json:"accepted"`
+	Edited         int64    `json:"edited"`
+	Discarded      int64    `json:"discarded"`
+	AcceptanceRate float64  `json:"acceptance_rate"` // (accepted + edited) / reported
+	AvgEditRatio   *float64 `json:"avg_edit_ratio"`  // over accepted + edited
+}
+
+type AnalyticsResponse struct {
+	Days            int            `json:"days"`
+	ByPromptVersion []OutcomeStats `json:"by_prompt_version"`
+	ByPurpose       []OutcomeStats `json:"by_purpose"`
+}
+
+// GetAIAnalytics handles GET /api/ai/analytics?days=30: the user's own generations.
+func GetAIAnalytics(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	return aiAnalytics(c, &userID)
+}
+
+// GetAIAnalyticsAll handles GET /admin/ai/analytics?days=30: every user, to
+// compare prompt versions.
+func GetAIAnalyticsAll(c *fiber.Ctx) error {
+	return aiAnalytics(c, nil)
+}
+
+func aiAnalytics(c *fiber.Ctx, userID *int64) error {
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 365 {
+		days = 30
+	}
+
+	params := map[string]any{"days": days, "userId": nil}
+	if userID != nil {
+		params["userId"] = *userID
+	}
+
+	// The same aggregation, grouped by prompt version or by each chosen purpose
+	match := `
+		MATCH (u:User)-[:GENERATED]->(g:Generation {type: 'comment'})
+		WHERE ($userId IS NULL OR u.id = $userId)
+		  AND g.created_at >= datetime() - duration({days: $days})
+	`
+	aggregate := `
+		RETURN key,
+		       count(g) AS generated,
+		       count(g.outcome) AS reported,
+		       sum(CASE g.outcome WHEN 'accepted' THEN 1 ELSE 0 END) AS accepted,
+		       sum(CASE g.outcome WHEN 'edited' THEN 1 ELSE 0 END) AS edited,
+		       sum(CASE g.outcome WHEN 'discarded' THEN 1 ELSE 0 END) AS discarded,
+		       avg(CASE WHEN g.outcome IN ['accepted', 'edited'] THEN g.edit_ratio END) AS avg_edit_ratio
+		ORDER BY generated DESC
+		LIMIT 50
+	`
+	byVersion, err := outcomeStats(match+`WITH g, coalesce(g.prompt_version, 'unknown') AS key`+aggregate, params)
+	if err != nil {
+		logger.Error("analytics query failed", "err", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	byPurpose, err := outcomeStats(match+`UNWIND coalesce(g.purposes, []) AS key WITH g, key`+aggregate, params)
+	if err != nil {
+		logger.Error("analytics query failed", "err", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+
+	return c.JSON(AnalyticsResponse{Days: days, ByPromptVersion: byVersion, ByPurpose: byPurpose})
+}
+
+func outcomeStats(query string, params map[string]any) ([]OutcomeStats, error) {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+		stats := []OutcomeStats{}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			s := OutcomeStats{Key: database.RecordString(r, "key")}
+			for field, dst := range map[string]*int64{
+				"generated": &s.Generated, "reported": &s.Reported, "accepted": &s.Accepted,
+				"edited": &s.Edited, "discarded": &s.Discarded,
+			} {
+				v, _ := r.Get(field)
+				*dst, _ = v.(int64)
+			}
+			if v, _ := r.Get("avg_edit_ratio"); v != nil {
+				ratio := v.(float64)
+				s.AvgEditRatio = &ratio
+			}
+			if s.Reported > 0 {
+				s.AcceptanceRate = float64(s.Accepted+s.Edited) / float64(s.Reported)
+			}
+			stats = append(stats, s)
+		}
+		return stats, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]OutcomeStats), nil
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"dashboard-server/database"
+	"dashboard-server/prompts"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// graphContext reads what the user's graph knows about a post: the author's
+// relationship with the user, co-commenters who bridge to people the user
+// already engages with (the Warm Reach paths of GetBridgeTargets, seen from
+// this post), and topics the user keeps coming back to.
+//
+// The post is looked up by URN; authorSlug is the fallback when the post has
+// not been ingested yet. Returns nil when the graph knows nothing useful.
+func graphContext(ctx context.Context, userID int64, postUrn, authorSlug string) (*prompts.GraphContext, error) {
+	if postUrn == "" && authorSlug == "" {
+		return nil, nil
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	params := map[string]any{"userId": userID, "postUrn": postUrn, "authorSlug": authorSlug}
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		gc := &prompts.GraphContext{}
+
+		// 1. Author and past interactions with their posts
+		rec, err := tx.Run(ctx, `
+			MATCH (u:User {id: $userId})
+			OPTIONAL MATCH (:Post {urn: $postUrn})-[:AUTHORED_BY]->(byPost:Person)
+			WITH u, coalesce(byPost.slug, $authorSlug) AS slug
+			MATCH (a:Person {slug: slug})
+			OPTIONAL MATCH (u)-[o:OBSERVED]->(a)
+			OPTIONAL MATCH (u)-[act:ACTION]->(:Post)-[:AUTHORED_BY]->(a)
+			RETURN a.name AS name,
+			       EXISTS { (u)-[:CONNECTED_TO]->(a) } AS connected,
+			       o.degree AS degree,
+			       count(act) AS interactions,
+			       max(act.timestamp) AS last_interaction
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Next(ctx) {
+			r := rec.Record()
+			interactions, _ := r.Get("interactions")
+			connected, _ := r.Get("connected")
+			gc.Author = &prompts.AuthorContext{
+				Name:         database.RecordString(r, "name"),
+				Degree:       database.RecordString(r, "degree"),
+				Interactions: interactions.(int64),
+			}
+			gc.Author.Connected, _ = connected.(bool)
+			if last, ok := recordTime(r, "last_interaction"); ok {
+				gc.Author.LastInteraction = last.Format("2006-01-02")
+			}
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		// 2. Co-commenters of this post who also comment on posts the user
+		//    acted on, or whose authors the user is connected to
+		rec, err = tx.Run(ctx, `
+			MATCH (u:User {id: $userId})
+			MATCH (c:Person)-[:COMMENTED_ON]->(:Post {urn: $postUrn})
+			MATCH (c)-[:COMMENTED_ON]->(p2:Post)-[:AUTHORED_BY]->(y:Person)
+			WHERE p2.urn <> $postUrn AND y <> c
+			  AND ((u)-[:ACTION]->(p2) OR (u)-[:CONNECTED_TO]->(y))
+			WITH u, c, collect(DISTINCT y.name) AS authors, count(DISTINCT p2) AS strength
+			RETURN c.name AS name, EXISTS { (u)-[:CONNECTED_TO]->(c) } AS connected,
+			       authors[..3] AS also_comments_on, strength
+			ORDER BY strength DESC
+			LIMIT 5
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			connected, _ := r.Get("connected")
+			b := prompts.BridgeContext{
+				Name:           database.RecordString(r, "name"),
+				AlsoCommentsOn: database.RecordStrings(r, "also_comments_on"),
+			}
+			b.Connected, _ = connected.(bool)
+			if b.Name != "" {
+				gc.Bridges = append(gc.Bridges, b)
+			}
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		// 3. Topics of this post the user already engaged with
+		rec, err = tx.Run(ctx, `
+			MATCH (:Post {urn: $postUrn})-[:HAS_TOPIC]->(t:Topic)
+			MATCH (:User {id: $userId})-[:ACTION]->(other:Post)-[:HAS_TOPIC]->(t)
+			WHERE other.urn <> $postUrn
+			RETURN t.name AS topic, count(DISTINCT other) AS posts
+			ORDER BY posts DESC
+			LIMIT 5
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			if topic := database.RecordString(rec.Record(), "topic"); topic != "" {
+				gc.Topics = append(gc.Topics, topic)
+			}
+		}
+		return gc, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gc := result.(*prompts.GraphContext)
+	if gc.Author == nil && len(gc.Bridges) == 0 && len(gc.Topics) == 0 {
+		return nil, nil
+	}
+	return gc, nil
+}
+
+// recordTime reads a temporal value, whichever way it was stored.
+func recordTime(r *neo4j.Record, key string) (time.Time, bool) {
+	v, _ := r.Get(key)
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case neo4j.LocalDateTime:
+		return t.Time(), true
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type JobItem struct {
+	ID          string `json:"id"`
+	Role        string `json:"role"`
+	Seniority   string `json:"seniority"`
+	Location    string `json:"location"`
+	Remote      string `json:"remote"`
+	CompanyName string `json:"company_name"`
+	CompanySlug string `json:"company_slug"`
+	PostUrn     string `json:"post_urn"`
+	AuthorName  string `json:"author_name"`
+	AuthorSlug  string `json:"author_slug"`
+	Connected   bool   `json:"connected"` // the author is a 1st degree connection
+	Degree      string `json:"degree"`    // as last seen by the user
+	AnnouncedAt string `json:"announced_at"`
+}
+
+// GetJobs handles GET /api/jobs?days=30&remote=remote&seniority=senior&q=frontend
+// Open roles extracted from hiring posts (see the enrichment package) whose
+// author is in the user's network: connected, or seen by the user in the feed.
+func GetJobs(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 365 {
+		days = 30
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (u:User {id: $userId})
+		MATCH (a:Person)<-[:AUTHORED_BY]-(p:Post)-[:ANNOUNCED_IN]->(j:Job)
+		WHERE ((u)-[:CONNECTED_TO]->(a) OR (u)-[:OBSERVED]->(a))
+		  AND j.announced_at IS NOT NULL
+		  AND datetime(j.announced_at) >= datetime() - duration({days: $days})
+		  AND ($remote = '' OR j.remote = $remote)
+		  AND ($seniority = '' OR j.seniority = $seniority)
+		  AND ($q = '' OR toLower(j.role) CONTAINS $q)
+		OPTIONAL MATCH (c:Company)-[:HIRING]->(j)
+		OPTIONAL MATCH (u)-[o:OBSERVED]->(a)
+		RETURN j.id AS id, j.role AS role, j.seniority AS seniority, j.location AS location, j.remote AS remote,
+		       coalesce(c.name, j.company_name) AS company_name, c.slug AS company_slug,
+		       p.urn AS post_urn, a.name AS author_name, a.slug AS author_slug,
+		       EXISTS { (u)-[:CONNECTED_TO]->(a) } AS connected, o.degree AS degree,
+		       j.announced_at AS announced_at
+		ORDER BY announced_at DESC
+		LIMIT 100
+		`
+		rec, err := tx.Run(ctx, query, map[string]any{
+			"userId":    userID,
+			"days":      days,
+			"remote":    c.Query("remote"),
+			"seniority": c.Query("seniority"),
+			"q":         strings.ToLower(strings.TrimSpace(c.Query("q"))),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		jobs := []JobItem{}
+		for rec.Next(ctx) {
+			jobs = append(jobs, jobItem(rec.Record()))
+		}
+		return jobs, rec.Err()
+	})
+
+	if err != nil {
+		logger.Error("jobs query failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+
+	return c.JSON(result)
+}
+
+func jobItem(r *neo4j.Record) JobItem {
+	connected, _ := r.Get("connected")
+	job := JobItem{
+		ID:          database.RecordString(r, "id"),
+		Role:        database.RecordString(r, "role"),
+		Seniority:   database.RecordString(r, "seniority"),
+		Location:    database.RecordString(r, "location"),
+		Remote:      database.RecordString(r, "remote"),
+		CompanyName: database.RecordString(r, "company_name"),
+		CompanySlug: database.RecordString(r, "company_slug"),
+		PostUrn:     database.RecordString(r, "post_urn"),
+		AuthorName:  database.RecordString(r, "author_name"),
+		AuthorSlug:  database.RecordString(r, "author_slug"),
+		Degree:      database.RecordString(r, "degree"),
+		AnnouncedAt: database.RecordString(r, "announced_at"),
+	}
+	job.Connected, _ = connected.(bool)
+	return job
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/prompts"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type PostDraftRequest struct {
+	Topic               string `json:"topic,omitempty"` // "" mines the user's topics
+	Angle               string `json:"angle,omitempty"` // e.g. one of the angles of a previous call
+	Notes               string `json:"notes,omitempty"` // the user's own idea, experience or data
+	Hooks               int    `json:"hooks,omitempty"` // hook variants, up to MaxPostHooks
+	Days                int    `json:"days,omitempty"`  // how far back to look for trending posts
+	ProfessionalContext string `json:"professionalContext,omitempty"`
+}
+
+type PostDraftResponse struct {
+	PostDraftResult
+	Topics       []TopicResponse        `json:"topics"` // what the draft was based on
+	Trending     []TrendingPostResponse `json:"trending"`
+	GenerationID string                 `json:"generation_id,omitempty"`
+}
+
+type TopicResponse struct {
+	Name  string `json:"name"`
+	Posts int64  `json:"posts"`
+}
+
+type TrendingPostResponse struct {
+	Urn        string   `json:"urn"`
+	AuthorName string   `json:"author_name"`
+	Engagement int64    `json:"engagement"`
+	Topics     []string `json:"topics"`
+}
+
+const (
+	MaxPostHooks     = 5
+	DefaultPostHooks = 3
+
+	postMaxText     = 600
+	postMaxNotes    = 2000
+	postMaxHashtags = 3
+	postTopTopics   = 5
+	postTrending    = 5
+	postTrendingLen = 600 // characters of each trending post sent to the model
+)
+
+// DraftPost handles POST /api/ai/draft-post: mines the user's most engaged
+// topics and the trending posts of their network, asks the model for angles
+// and a draft with hook variants. One call is one metered use.
+func DraftPost(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
+
+	var req PostDraftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
+	}
+	req.Topic = truncateRunes(strings.TrimSpace(req.Topic), styleMaxPhraseText)
+	req.Angle = truncateRunes(strings.TrimSpace(req.Angle), styleMaxText)
+	req.Notes = truncateRunes(strings.TrimSpace(req.Notes), postMaxNotes)
+	if req.Hooks < 1 || req.Hooks > MaxPostHooks {
+		req.Hooks = DefaultPostHooks
+	}
+	if req.Days < 1 || req.Days > 90 {
+		req.Days = 14
+	}
+
+	tier, _ := c.Locals("tier").(string)
+
+	ctx, cancel := aiContext(c)
+	defer cancel()
+	provider, byok := resolveProvider(ctx, userID, llm.FeaturePost, tier)
+	if !byok && limitReached(userID, tier) {
+		return c.Status(403).JSON(limitReachedResponse(lang))
+	}
+
+	ideation, trending, err := mineIdeation(ctx, userID, req)
+	if err != nil {
+		// The model can still work from the user's topic, notes and profile
+		logger.Warn("failed to mine topic graph", "err", err, "user_id", userID)
+	}
+	if ideation.Topic == "" && len(ideation.Topics) == 0 && ideation.Notes == "" {
+		return c.Status(422).JSON(fiber.Map{"error": i18n.T(lang, "error.no_topics")})
+	}
+
+	data := promptData(ctx, userID, AIRequest{ProfessionalContext: req.ProfessionalContext}, lang)
+	data.Ideation = ideation
+	prompt, err := prompts.Render(prompts.Post, userID, data)
+	if err != nil {
+		logger.Error("prompt render failed", "err", err, "prompt", prompts.Post)
+		return c.Status(500).JSON(fiber.Map{"error": i18n.T(lang, "error.prompt")})
+	}
+
+	result, _, err := completeStructured[PostDraftResult](ctx, provider, llm.UserRequest(prompt.System, prompt.User), postDraftSchema, prompt.Language)
+	if err != nil {
+		logger.Error("post draft failed", "err", err, "user_id", userID, "prompt_version", prompt.Version)
+		return aiErrorResponse(c, lang, err)
+	}
+	result.Draft.Hooks = result.Draft.Hooks[:min(len(result.Draft.Hooks), req.Hooks)]
+
+	// The recorded text is the draft as it would be posted with the first hook
+	generationID, _, err := recordGeneration(generationRecord{
+		UserID:        userID,
+		Type:          "post",
+		BYOK:          byok,
+		PromptVersion: prompt.Version,
+		Language:      lang,
+		Purposes:      topicNames(ideation),
+		Text:          result.Draft.Hooks[0] + "\n\n" + result.Draft.Body,
+		Angle:         result.Draft.Angle,
+	})
+	if err != nil {
+		logger.Error("failed to record generation", "err", err, "user_id", userID)
+	}
+
+	resp := PostDraftResponse{PostDraftResult: result, Topics: []TopicResponse{}, Trending: trending, GenerationID: generationID}
+	for _, t := range ideation.Topics {
+		resp.Topics = append(resp.Topics, TopicResponse{Name: t.Name, Posts: t.Posts})
+	}
+	return c.JSON(resp)
+}
+
+// mineIdeation reads the user's most engaged topics over the last req.Days
+// and the posts with most interactions in their network (authors they are
+// connected to or have seen), restricted to the chosen topic or to the mined
+// ones. The returned Ideation is never nil.
+func mineIdeation(ctx context.Context, userID int64, req PostDraftRequest) (*prompts.Ideation, []TrendingPostResponse, error) {
+	ideation := &prompts.Ideation{Topic: req.Topic, Angle: req.Angle, Notes: req.Notes, Hooks: req.Hooks}
+	var trending []TrendingPostResponse
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// The function can be retried: start from empty lists every time
+		ideation.Topics, ideation.Trending, trending = nil, nil, []TrendingPostResponse{}
+
+		rec, err := tx.Run(ctx, `
+			MATCH (:User {id: $userId})-[a:ACTION]->(p:Post)-[:HAS_TOPIC]->(t:Topic)
+			WHERE datetime(a.timestamp) >= datetime() - duration({days: $days})
+			RETURN t.name AS topic, count(DISTINCT p) AS posts
+			ORDER BY posts DESC
+			LIMIT $limit
+		`, map[string]any{"userId": userID, "days": req.Days, "limit": postTopTopics})
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			posts, _ := r.Get("posts")
+			t := prompts.TopicStat{Name: database.RecordString(r, "topic")}
+			t.Posts, _ = posts.(int64)
+			ideation.Topics = append(ideation.Topics, t)
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		topics := topicNames(ideation)
+		rec, err = tx.Run(ctx, `
+			MATCH (u:User {id: $userId})
+			MATCH (p:Post)-[:AUTHORED_BY]->(a:Person)
+			WHERE ((u)-[:CONNECTED_TO]->(a) OR (u)-[:OBSERVED]->(a))
+			  AND p.text IS NOT NULL AND p.first_seen IS NOT NULL
+			  AND datetime(p.first_seen) >= datetime() - duration({days: $days})
+			  AND (size($topics) = 0 OR EXISTS {
+			    MATCH (p)-[:HAS_TOPIC]->(t:Topic) WHERE toLower(t.name) IN [x IN $topics | toLower(x)]
+			  })
+			WITH p, a, COUNT { (:Person)-[:COMMENTED_ON]->(p) } + COUNT { (:Person)-[:AMPLIFIED]->(p) } AS engagement
+			WHERE engagement > 0
+			RETURN p.urn AS urn, a.name AS author, left(p.text, $textLen) AS text, engagement,
+			       [(p)-[:HAS_TOPIC]->(t:Topic) | t.name] AS topics
+			ORDER BY engagement DESC
+			LIMIT $limit
+		`, map[string]any{"userId": userID, "days": req.Days, "topics": topics, "textLen": postTrendingLen, "limit": postTrending})
+		if err != nil {
+			return nil, err
+		}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			engagement, _ := r.Get("engagement")
+			post := prompts.TrendingPost{
+				AuthorName: database.RecordString(r, "author"),
+				Text:       database.RecordString(r, "text"),
+				Topics:     database.RecordStrings(r, "topics"),
+			}
+			post.Engagement, _ = engagement.(int64)
+			ideation.Trending = append(ideation.Trending, post)
+			trending = append(trending, TrendingPostResponse{
+				Urn:        database.RecordString(r, "urn"),
+				AuthorName: post.AuthorName,
+				Engagement: post.Engagement,
+				Topics:     post.Topics,
+			})
+		}
+		return nil, rec.Err()
+	})
+	if trending == nil {
+		trending = []TrendingPostResponse{}
+	}
+	return ideation, trending, err
+}
+
+func topicNames(ideation *prompts.Ideation) []string {
+	if ideation.Topic != "" {
+		return []string{ideation.Topic}
+	}
+	names := make([]string, 0, len(ideation.Topics))
+	for _, t := range ideation.Topics {
+		names = append(names, t.Name)
+	}
+	return names
+}
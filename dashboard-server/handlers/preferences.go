@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type PreferencesRequest struct {
+	Language string `json:"language"`
+}
+
+type PreferencesResponse struct {
+	Language  string          `json:"language"`
+	Languages []i18n.Language `json:"languages"`
+}
+
+// GetPreferences handles GET /api/user/preferences
+func GetPreferences(c *fiber.Ctx) error {
+	return c.JSON(PreferencesResponse{
+		Language:  userLanguage(c, middleware.UserID(c)),
+		Languages: i18n.Languages(),
+	})
+}
+
+// UpdatePreferences handles PUT /api/user/preferences
+func UpdatePreferences(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
+
+	var req PreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
+	}
+	code := i18n.Normalize(req.Language)
+	if !i18n.Supported(code) {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.unsupported_language")})
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return nil, runUserWrite(ctx, tx, `MATCH (u:User {id: $userId}) SET u.language = $language RETURN u.id`,
+			map[string]any{"userId": userID, "language": code})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": i18n.T(lang, "error.user_not_found")})
+		}
+		logger.Error("preferences update failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+
+	return c.JSON(PreferencesResponse{Language: code, Languages: i18n.Languages()})
+}
+
+// errUserNotFound means the user has no node in the graph yet: event-service
+// creates it when it stores the user's first events, and it is gone for good
+// once the account is deleted.
+var errUserNotFound = errors.New("user not found")
+
+// runUserWrite runs a write that MATCHes the user's node, so it never brings
+// back a deleted account. The query must return a row when it matched.
+func runUserWrite(ctx context.Context, tx neo4j.ManagedTransaction, query string, params map[string]any) error {
+	rec, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return err
+	}
+	if rec.Next(ctx) {
+		return nil
+	}
+	if err := rec.Err(); err != nil {
+		return err
+	}
+	return errUserNotFound
+}
+
+// userLanguage is the user's saved language, else the browser's
+// Accept-Language, else i18n.Default.
+func userLanguage(c *fiber.Ctx, userID int64) string {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	saved, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `MATCH (u:User {id: $userId}) RETURN u.language AS language`,
+			map[string]any{"userId": userID})
+		if err != nil {
+			return "", err
+		}
+		if rec.Next(ctx) {
+			language, _ := rec.Record().Get("language")
+			s, _ := language.(string)
+			return s, nil
+		}
+		return "", rec.Err()
+	})
+	if err != nil {
+		logger.Warn("failed to read user language", "err", err, "user_id", userID)
+	} else if code := saved.(string); i18n.Supported(code) {
+		return code
+	}
+
+	if code := i18n.FromAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)); code != "" {
+		return code
+	}
+	return i18n.Default
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/prompts"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Variants and regeneration of a comment.
+//
+// Every comment generation belongs to a session: a fresh request starts one,
+// and a request with regenerateFrom joins the session of the generation it
+// rewrites. The free-tier limit counts sessions, not generations, so the
+// variants of one request and its later rewrites are a single use.
+const (
+	MaxVariants = 3
+	// MaxSessionGenerations caps what one metered use can produce, variants included.
+	MaxSessionGenerations = 20
+
+	instructionMaxText = 300
+)
+
+// Preset regeneration instructions offered by the plugin. Anything else is
+// passed to the model as free text.
+var regenerateInstructions = map[string]string{
+	"shorter":        "rendilo più breve, al massimo due frasi, mantenendo il punto principale.",
+	"more_technical": "rendilo più tecnico e specifico, con un dettaglio concreto pertinente al post.",
+	"add_question":   "aggiungi alla fine una domanda aperta e pertinente rivolta all'autore.",
+}
+
+const defaultRegenerateInstruction = "proponi una versione diversa, con un angolo nuovo."
+
+// normalizeInstruction trims and caps a free-text instruction; presets are kept as-is.
+func normalizeInstruction(instruction string) string {
+	return truncateRunes(strings.TrimSpace(instruction), instructionMaxText)
+}
+
+// regeneration builds the prompt block for a rewrite of prev.
+func regeneration(prev *previousGeneration, instruction string) *prompts.Regeneration {
+	text, ok := regenerateInstructions[instruction]
+	if !ok {
+		text = instruction
+	}
+	if text == "" {
+		text = defaultRegenerateInstruction
+	}
+	return &prompts.Regeneration{PreviousText: prev.Text, Instruction: text}
+}
+
+// previousGeneration is a comment generation being regenerated.
+type previousGeneration struct {
+	ID           string
+	Text         string
+	SessionID    string
+	PostUrn      string
+	PostText     string // as stored with the post, "" if not stored
+	Generations  int64  // generations already in the session
+	StartedToday bool   // the session was metered today
+}
+
+// loadPreviousGeneration returns one of the user's comment generations with
+// its session, or nil if the user has no such generation.
+func loadPreviousGeneration(ctx context.Context, userID int64, generationID string) (*previousGeneration, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// Generations recorded before sessions existed are their own session.
+		query := `
+		MATCH (u:User {id: $userId})-[:GENERATED]->(prev:Generation {id: $id, type: 'comment'})
+		WITH u, prev, coalesce(prev.session_id, prev.id) AS sessionId
+		OPTIONAL MATCH (prev)-[:FOR_POST]->(p:Post)
+		OPTIONAL MATCH (u)-[:GENERATED]->(s:Generation)
+		WHERE coalesce(s.session_id, s.id) = sessionId
+		RETURN coalesce(prev.text, '') AS text, sessionId AS session_id,
+		       prev.post_urn AS recorded_post_urn, head(collect(DISTINCT p.urn)) AS post_urn, head(collect(DISTINCT p.text)) AS post_text,
+		       count(DISTINCT s) AS generations, date(min(s.created_at)) = date() AS started_today
+		`
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID, "id": generationID})
+		if err != nil {
+			return nil, err
+		}
+		if !rec.Next(ctx) {
+			return (*previousGeneration)(nil), rec.Err()
+		}
+		r := rec.Record()
+		generations, _ := r.Get("generations")
+		prev := &previousGeneration{
+			ID:        generationID,
+			Text:      database.RecordString(r, "text"),
+			SessionID: database.RecordString(r, "session_id"),
+			PostUrn:   database.RecordString(r, "post_urn"),
+			PostText:  database.RecordString(r, "post_text"),
+		}
+		if urn := database.RecordString(r, "recorded_post_urn"); urn != "" {
+			// Generations keep the URN even when the post is not stored
+			prev.PostUrn = urn
+		}
+		prev.Generations, _ = generations.(int64)
+		startedToday, _ := r.Get("started_today")
+		prev.StartedToday, _ = startedToday.(bool)
+		return prev, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*previousGeneration), nil
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/prompts"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// ReplyComment is a comment received on the user's own post, as scraped by the plugin.
+type ReplyComment struct {
+	CommentUrn     string `json:"commentUrn,omitempty"`
+	AuthorName     string `json:"authorName"`
+	AuthorSlug     string `json:"authorSlug,omitempty"`
+	AuthorHeadline string `json:"authorHeadline,omitempty"`
+	Text           string `json:"text"`
+}
+
+type ReplyRequest struct {
+	PostUrn             string         `json:"postUrn,omitempty"`
+	PostText            string         `json:"postText"`
+	ProfessionalContext string         `json:"professionalContext,omitempty"`
+	UserName            string         `json:"userName,omitempty"`
+	Comments            []ReplyComment `json:"comments"`
+	MaxReplies          int            `json:"maxReplies,omitempty"` // drafts to write, up to MaxReplyDrafts
+}
+
+// RankedComment is a received comment with its priority. The reply fields are
+// only set for the comments that got a draft.
+type RankedComment struct {
+	ReplyComment
+	Score        int      `json:"score"`
+	Reasons      []string `json:"reasons"` // connection, bridge, vip
+	Reply        string   `json:"reply,omitempty"`
+	Translation  string   `json:"translation,omitempty"`
+	GenerationID string   `json:"generation_id,omitempty"`
+}
+
+type RepliesResponse struct {
+	SessionID string          `json:"session_id,omitempty"`
+	Comments  []RankedComment `json:"comments"` // by priority, highest first
+}
+
+const (
+	MaxReplyComments = 50
+	MaxReplyDrafts   = 5
+
+	replyMaxText = 2000
+)
+
+// Priority of a commenter, from the user's graph and their parsed headline.
+const (
+	ReasonConnection = "connection" // 1st degree connection
+	ReasonBridge     = "bridge"     // comments on posts the user engages with
+	ReasonVIP        = "vip"        // senior or strategic role (see commenter.vip)
+
+	replyScoreConnection = 3
+	replyScoreBridge     = 2
+	replyScoreVIP        = 2
+)
+
+// DraftReplies handles POST /api/ai/draft-replies: the plugin sends the
+// comments received on one of the user's posts, the server ranks them by the
+// commenters' relevance and drafts replies for the top ones, in one model call.
+// The whole batch is one session, metered as a single use.
+func DraftReplies(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	lang := userLanguage(c, userID)
+
+	var req ReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(lang, "error.invalid_body")})
+	}
+	req.Comments = cleanReplyComments(req.Comments)
+	if len(req.Comments) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "comments is required"})
+	}
+	if req.MaxReplies < 1 || req.MaxReplies > MaxReplyDrafts {
+		req.MaxReplies = MaxReplyDrafts
+	}
+
+	tier, _ := c.Locals("tier").(string)
+
+	ctx, cancel := aiContext(c)
+	defer cancel()
+	provider, byok := resolveProvider(ctx, userID, llm.FeatureReply, tier)
+	if !byok && limitReached(userID, tier) {
+		return c.Status(403).JSON(limitReachedResponse(lang))
+	}
+
+	ranked, err := rankReplyComments(ctx, userID, req.Comments)
+	if err != nil {
+		// Ranking is a nice-to-have: fall back to the plugin's order
+		logger.Warn("failed to rank comments", "err", err, "user_id", userID)
+	}
+
+	data := promptData(ctx, userID, AIRequest{
+		PostText:            req.PostText,
+		ProfessionalContext: req.ProfessionalContext,
+		UserName:            req.UserName,
+	}, lang)
+	drafts := min(req.MaxReplies, len(ranked))
+	for i, rc := range ranked[:drafts] {
+		data.Replies = append(data.Replies, prompts.ReplyTarget{
+			Index:      i + 1,
+			AuthorName: rc.AuthorName,
+			Headline:   rc.AuthorHeadline,
+			Text:       rc.Text,
+			Connected:  slices.Contains(rc.Reasons, ReasonConnection),
+			Bridge:     slices.Contains(rc.Reasons, ReasonBridge),
+			VIP:        slices.Contains(rc.Reasons, ReasonVIP),
+		})
+	}
+	prompt, err := prompts.Render(prompts.Reply, userID, data)
+	if err != nil {
+		logger.Error("prompt render failed", "err", err, "prompt", prompts.Reply)
+		return c.Status(500).JSON(fiber.Map{"error": i18n.T(lang, "error.prompt")})
+	}
+
+	record := func(g generationRecord) (string, string) {
+		g.UserID, g.Type, g.PostUrn, g.BYOK = userID, "reply", req.PostUrn, byok
+		g.PromptVersion, g.Language = prompt.Version, lang
+		generationID, sessionID, err := recordGeneration(g)
+		if err != nil {
+			logger.Error("failed to record generation", "err", err, "user_id", userID)
+		}
+		return generationID, sessionID
+	}
+
+	result, _, err := completeStructured[RepliesResult](ctx, provider, llm.UserRequest(prompt.System, prompt.User), repliesSchema, prompt.Language)
+	if err != nil {
+		logger.Error("reply drafting failed", "err", err, "user_id", userID, "prompt_version", prompt.Version)
+		var aerr *aiError
+		if errors.As(err, &aerr) && aerr.Code == CodeInvalidOutput {
+			// The model answered, so the call is metered all the same
+			record(generationRecord{})
+		}
+		return aiErrorResponse(c, lang, err)
+	}
+
+	resp := RepliesResponse{Comments: ranked}
+	drafted := false
+	for _, draft := range result.Replies {
+		if draft.Index < 1 || draft.Index > drafts || ranked[draft.Index-1].Reply != "" {
+			continue
+		}
+		rc := &ranked[draft.Index-1]
+		rc.Reply, rc.Translation = draft.Reply, draft.Translation
+
+		generationID, sessionID := record(generationRecord{
+			PostLanguage: i18n.Detect(rc.Text),
+			Text:         draft.Reply,
+			SessionID:    resp.SessionID,
+			ReplyTo:      rc.CommentUrn,
+		})
+		rc.GenerationID = generationID
+		drafted = true
+		if resp.SessionID == "" {
+			resp.SessionID = sessionID
+		}
+	}
+	if !drafted {
+		// No usable draft (indices out of range, repeated), still a model call
+		_, resp.SessionID = record(generationRecord{})
+	}
+	return c.JSON(resp)
+}
+
+func cleanReplyComments(comments []ReplyComment) []ReplyComment {
+	var out []ReplyComment
+	for _, rc := range comments {
+		rc.Text = truncateRunes(strings.TrimSpace(rc.Text), replyMaxText)
+		rc.AuthorName = strings.TrimSpace(rc.AuthorName)
+		rc.AuthorSlug = strings.TrimSpace(rc.AuthorSlug)
+		if rc.Text == "" {
+			continue
+		}
+		out = append(out, rc)
+		if len(out) == MaxReplyComments {
+			break
+		}
+	}
+	return out
+}
+
+// rankReplyComments scores every comment by its author's relevance to the
+// user. Ties keep the plugin's order. On graph errors every comment scores 0
+// and the error is returned with the ranking.
+func rankReplyComments(ctx context.Context, userID int64, comments []ReplyComment) ([]RankedComment, error) {
+	relevance, err := commenterRelevance(ctx, userID, comments)
+
+	ranked := make([]RankedComment, len(comments))
+	for i, rc := range comments {
+		r := relevance[rc.AuthorSlug]
+		if rc.AuthorHeadline == "" {
+			rc.AuthorHeadline = r.Headline
+		}
+		ranked[i] = RankedComment{ReplyComment: rc, Reasons: []string{}}
+		if r.Connected {
+			ranked[i].Score += replyScoreConnection
+			ranked[i].Reasons = append(ranked[i].Reasons, ReasonConnection)
+		}
+		if r.SharedPosts > 0 {
+			ranked[i].Score += replyScoreBridge
+			ranked[i].Reasons = append(ranked[i].Reasons, ReasonBridge)
+		}
+		if r.vip() {
+			ranked[i].Score += replyScoreVIP
+			ranked[i].Reasons = append(ranked[i].Reasons, ReasonVIP)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked, err
+}
+
+type commenter struct {
+	Headline     string
+	Seniority    string // Person.seniority, as parsed by event-service
+	RoleCategory string // Person.role_category
+	Connected    bool
+	SharedPosts  int64 // posts the user acted on that the commenter also commented
+}
+
+// vip reports whether the commenter holds a senior or strategic role: an
+// executive or lead by seniority, or an investor, as the headline taxonomy
+// of event-service classified them. People not parsed yet are not VIPs.
+func (cm commenter) vip() bool {
+	return cm.Seniority == "executive" || cm.Seniority == "lead" || cm.RoleCategory == "investor"
+}
+
+func commenterRelevance(ctx context.Context, userID int64, comments []ReplyComment) (map[string]commenter, error) {
+	var slugs []string
+	for _, rc := range comments {
+		if rc.AuthorSlug != "" {
+			slugs = append(slugs, rc.AuthorSlug)
+		}
+	}
+	if len(slugs) == 0 {
+		return map[string]commenter{}, nil
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (u:User {id: $userId})
+		UNWIND $slugs AS slug
+		MATCH (p:Person {slug: slug})
+		RETURN DISTINCT p.slug AS slug, p.headline AS headline,
+		       p.seniority AS seniority, p.role_category AS role_category,
+		       EXISTS { (u)-[:CONNECTED_TO]->(p) } AS connected,
+		       COUNT { (u)-[:ACTION]->(:Post)<-[:COMMENTED_ON]-(p) } AS shared_posts
+		`
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID, "slugs": slugs})
+		if err != nil {
+			return nil, err
+		}
+		out := map[string]commenter{}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			connected, _ := r.Get("connected")
+			shared, _ := r.Get("shared_posts")
+			cm := commenter{
+				Headline:     database.RecordString(r, "headline"),
+				Seniority:    database.RecordString(r, "seniority"),
+				RoleCategory: database.RecordString(r, "role_category"),
+			}
+			cm.Connected, _ = connected.(bool)
+			cm.SharedPosts, _ = shared.(int64)
+			out[database.RecordString(r, "slug")] = cm
+		}
+		return out, rec.Err()
+	})
+	if err != nil {
+		return map[string]commenter{}, err
+	}
+	return result.(map[string]commenter), nil
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// RoleCategory is a user-defined bucket for the network insights, on top of
+// the built-in taxonomy of event-service (Person.role_category). Terms are
+// matched as whole words on the connection's job title, then on the headline;
+// categories are tried in order and the first match wins.
+//
+// The list is stored as JSON in User.role_categories, so it goes away with
+// the account. Each connection's bucket is precomputed on the user's own
+// CONNECTED_TO edge (custom_category, custom_category_version), since the
+// same person falls in different buckets for different users: when the list
+// is saved, then by StartRoleClassification for new connections and
+// re-parsed headlines.
+type RoleCategory struct {
+	Name  string   `json:"name"`
+	Terms []string `json:"terms"`
+}
+
+const (
+	maxRoleCategories   = 20
+	maxRoleCategoryTerm = 30 // terms per category
+
+	roleClassificationInterval = 10 * time.Minute
+)
+
+// GetRoleCategories handles GET /api/user/role-categories
+func GetRoleCategories(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	categories, _, err := loadRoleCategories(context.Background(), userID)
+	if err != nil {
+		logger.Error("role categories load failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	return c.JSON(categories)
+}
+
+// UpdateRoleCategories handles PUT /api/user/role-categories (replaces the
+// whole list; an empty list removes it)
+func UpdateRoleCategories(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+
+	var categories []RoleCategory
+	if err := c.BodyParser(&categories); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.invalid_body")})
+	}
+	categories = cleanRoleCategories(categories)
+
+	var stored, version any
+	if len(categories) > 0 {
+		data, _ := json.Marshal(categories)
+		sum := sha256.Sum256(data)
+		stored, version = string(data), hex.EncodeToString(sum[:6])
+	}
+
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return nil, runUserWrite(ctx, tx, `
+			MATCH (u:User {id: $userId})
+			SET u.role_categories = $categories, u.role_categories_version = $version
+			RETURN u.id
+		`, map[string]any{"userId": userID, "categories": stored, "version": version})
+	})
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.user_not_found")})
+		}
+		logger.Error("role categories save failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	if err := classifyCustomCategories(ctx, userID); err != nil {
+		// StartRoleClassification catches up on its next run
+		logger.Warn("custom role classification failed", "err", err, "user_id", userID)
+	}
+	return c.JSON(categories)
+}
+
+// cleanRoleCategories trims names, normalizes terms and drops empty or
+// duplicate categories.
+func cleanRoleCategories(categories []RoleCategory) []RoleCategory {
+	out := []RoleCategory{}
+	seen := map[string]bool{}
+	for _, rc := range categories {
+		rc.Name = truncateRunes(strings.TrimSpace(rc.Name), styleMaxPhraseText)
+		var terms []string
+		for _, term := range cleanList(rc.Terms, maxRoleCategoryTerm, styleMaxPhraseText) {
+			if t := strings.TrimSpace(normalizeWords(term)); t != "" {
+				terms = append(terms, t)
+			}
+		}
+		if rc.Name == "" || len(terms) == 0 || seen[strings.ToLower(rc.Name)] {
+			continue
+		}
+		seen[strings.ToLower(rc.Name)] = true
+		rc.Terms = terms
+		out = append(out, rc)
+		if len(out) == maxRoleCategories {
+			break
+		}
+	}
+	return out
+}
+
+// loadRoleCategories returns the user's categories and their version ("" when
+// the user has none).
+func loadRoleCategories(ctx context.Context, userID int64) ([]RoleCategory, string, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	categories := []RoleCategory{}
+	var version string
+	_, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (u:User {id: $userId})
+			RETURN u.role_categories AS categories, u.role_categories_version AS version
+		`, map[string]any{"userId": userID})
+		if err != nil {
+			return nil, err
+		}
+		if rec.Next(ctx) {
+			r := rec.Record()
+			if data := database.RecordString(r, "categories"); data != "" {
+				if err := json.Unmarshal([]byte(data), &categories); err != nil {
+					return nil, err
+				}
+			}
+			version = database.RecordString(r, "version")
+		}
+		return nil, rec.Err()
+	})
+	return categories, version, err
+}
+
+// StartRoleClassification keeps the custom categories of every user with
+// some up to date, every roleClassificationInterval, until the process exits.
+func StartRoleClassification() {
+	ticker := time.NewTicker(roleClassificationInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx := context.Background()
+		users, err := usersToClassify(ctx)
+		if err != nil {
+			logger.Error("role classification lookup failed", "err", err)
+			continue
+		}
+		for _, userID := range users {
+			if err := classifyCustomCategories(ctx, userID); err != nil {
+				logger.Warn("custom role classification failed", "err", err, "user_id", userID)
+			}
+		}
+	}
+}
+
+// usersToClassify returns the users with connections classifyCustomCategories
+// would write.
+func usersToClassify(ctx context.Context) ([]int64, error) {
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (u:User) WHERE u.role_categories_version IS NOT NULL
+			  AND EXISTS {
+			    MATCH (u)-[r:CONNECTED_TO]->(p:Person)
+			    WHERE coalesce(r.custom_category_version, '') <> u.role_categories_version
+			       OR r.custom_category_at < p.headline_parsed_at
+			  }
+			RETURN u.id AS id
+		`, nil)
+		if err != nil {
+			return nil, err
+		}
+		var users []int64
+		for rec.Next(ctx) {
+			users = append(users, database.RecordInt(rec.Record(), "id"))
+		}
+		return users, rec.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	users, _ := result.([]int64)
+	return users, nil
+}
+
+// classifyCustomCategories brings the user's connections up to date with
+// their categories: only the edges classified with another version (new
+// connections, or categories changed since) or before the person's headline
+// was last parsed are computed and written.
+func classifyCustomCategories(ctx context.Context, userID int64) error {
+	categories, version, err := loadRoleCategories(ctx, userID)
+	if err != nil || version == "" {
+		return err
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (:User {id: $userId})-[r:CONNECTED_TO]->(p:Person)
+			WHERE coalesce(r.custom_category_version, '') <> $version
+			   OR r.custom_category_at < p.headline_parsed_at
+			RETURN p.slug AS slug, p.job_title AS title, p.headline AS headline
+		`, map[string]any{"userId": userID, "version": version})
+		if err != nil {
+			return nil, err
+		}
+		var rows []map[string]any
+		for rec.Next(ctx) {
+			r := rec.Record()
+			category := matchRoleCategory(categories, database.RecordString(r, "title"))
+			if category == "" {
+				category = matchRoleCategory(categories, database.RecordString(r, "headline"))
+			}
+			rows = append(rows, map[string]any{"slug": database.RecordString(r, "slug"), "category": database.NullIfEmpty(category)})
+		}
+		if err := rec.Err(); err != nil || len(rows) == 0 {
+			return nil, err
+		}
+
+		_, err = tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (:User {id: $userId})-[r:CONNECTED_TO]->(:Person {slug: row.slug})
+			SET r.custom_category = row.category, r.custom_category_version = $version, r.custom_category_at = datetime()
+		`, map[string]any{"userId": userID, "version": version, "rows": rows})
+		return nil, err
+	})
+	return err
+}
+
+// matchRoleCategory returns the name of the first category with a term in
+// text, as whole words.
+func matchRoleCategory(categories []RoleCategory, text string) string {
+	if text == "" {
+		return ""
+	}
+	normalized := normalizeWords(text)
+	for _, rc := range categories {
+		for _, term := range rc.Terms {
+			if strings.Contains(normalized, " "+term+" ") {
+				return rc.Name
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeWords lowercases s and joins its letters and digits with single
+// spaces, padded on both sides for whole-word matching: "Co-Founder, AI"
+// becomes " co founder ai ".
+func normalizeWords(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"dashboard-server/database"
 	"dashboard-server/logger"
+	"dashboard-server/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -23,7 +23,7 @@ type StatsResponse struct {
 // GetStats handles GET /api/stats
 // Returns activity counters for the authenticated user.
 func GetStats(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	start := time.Now()
 
 	ctx := context.Background()
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dashboard-server/i18n"
+	"dashboard-server/llm"
+	"dashboard-server/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Error codes returned by the AI endpoints alongside "error", so the plugin
+// can tell a provider outage from a model that ignored the output format.
+const (
+	CodeProviderError = "provider_error"
+	CodeInvalidOutput = "invalid_output"
+)
+
+// PurposesResult is the output of SuggestPurposes.
+type PurposesResult struct {
+	Title    string   `json:"title"`
+	Purposes []string `json:"purposes"`
+}
+
+func (r *PurposesResult) validate() error {
+	var purposes []string
+	for _, p := range r.Purposes {
+		if p = strings.TrimSpace(p); p != "" {
+			purposes = append(purposes, p)
+		}
+	}
+	r.Purposes = purposes
+	if len(r.Purposes) == 0 {
+		return errors.New(`"purposes" must contain at least one non-empty string`)
+	}
+	return nil
+}
+
+// CommentResult is the output of GenerateComment.
+type CommentResult struct {
+	Comment     string `json:"comment"`
+	Translation string `json:"translation"`
+}
+
+// CommentResponse is what the comment endpoints return. With variants, the
+// top-level comment is the first one and Variants lists all of them.
+type CommentResponse struct {
+	CommentResult
+	GenerationID string                   `json:"generation_id,omitempty"`
+	SessionID    string                   `json:"session_id,omitempty"`
+	Variants     []CommentVariantResponse `json:"variants,omitempty"`
+}
+
+type CommentVariantResponse struct {
+	CommentVariant
+	GenerationID string `json:"generation_id,omitempty"`
+}
+
+func (r *CommentResult) validate() error {
+	r.Comment = strings.TrimSpace(r.Comment)
+	r.Translation = strings.TrimSpace(r.Translation)
+	if r.Comment == "" {
+		return errors.New(`"comment" must be a non-empty string`)
+	}
+	if r.Translation == "" {
+		return errors.New(`"translation" must be a non-empty string`)
+	}
+	return nil
+}
+
+// CommentVariant is one of the alternative comments of a variants request.
+type CommentVariant struct {
+	Angle string `json:"angle"`
+	CommentResult
+}
+
+// VariantsResult is the output of GenerateComment with variants > 1.
+type VariantsResult struct {
+	Variants []CommentVariant `json:"variants"`
+}
+
+func (r *VariantsResult) validate() error {
+	if len(r.Variants) == 0 {
+		return errors.New(`"variants" must contain at least one comment`)
+	}
+	for i := range r.Variants {
+		r.Variants[i].Angle = strings.TrimSpace(r.Variants[i].Angle)
+		if err := r.Variants[i].validate(); err != nil {
+			return fmt.Errorf("variant %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// RepliesResult is the output of DraftReplies. Index refers to the numbered
+// comments of the prompt.
+type RepliesResult struct {
+	Replies []ReplyDraft `json:"replies"`
+}
+
+type ReplyDraft struct {
+	Index       int    `json:"index"`
+	Reply       string `json:"reply"`
+	Translation string `json:"translation"`
+}
+
+func (r *RepliesResult) validate() error {
+	if len(r.Replies) == 0 {
+		return errors.New(`"replies" must contain at least one reply`)
+	}
+	for i := range r.Replies {
+		d := &r.Replies[i]
+		d.Reply = strings.TrimSpace(d.Reply)
+		d.Translation = strings.TrimSpace(d.Translation)
+		if d.Index < 1 || d.Reply == "" || d.Translation == "" {
+			return fmt.Errorf(`reply %d: "index" must be a comment number and "reply", "translation" non-empty strings`, i+1)
+		}
+	}
+	return nil
+}
+
+// PostDraftResult is the output of DraftPost.
+type PostDraftResult struct {
+	Angles []PostAngle `json:"angles"`
+	Draft  PostDraft   `json:"draft"`
+}
+
+type PostAngle struct {
+	Title     string `json:"title"`
+	Rationale string `json:"rationale"`
+}
+
+type PostDraft struct {
+	Angle    string   `json:"angle"`
+	Hooks    []string `json:"hooks"`
+	Body     string   `json:"body"`
+	Hashtags []string `json:"hashtags"`
+}
+
+func (r *PostDraftResult) validate() error {
+	var angles []PostAngle
+	for _, a := range r.Angles {
+		a.Title, a.Rationale = strings.TrimSpace(a.Title), strings.TrimSpace(a.Rationale)
+		if a.Title != "" {
+			angles = append(angles, a)
+		}
+	}
+	r.Angles = angles
+	r.Draft.Angle = strings.TrimSpace(r.Draft.Angle)
+	r.Draft.Body = strings.TrimSpace(r.Draft.Body)
+	r.Draft.Hooks = cleanList(r.Draft.Hooks, MaxPostHooks, postMaxText)
+	r.Draft.Hashtags = cleanList(r.Draft.Hashtags, postMaxHashtags, styleMaxPhraseText)
+	switch {
+	case len(r.Angles) == 0:
+		return errors.New(`"angles" must contain at least one angle with a title`)
+	case len(r.Draft.Hooks) == 0:
+		return errors.New(`"draft.hooks" must contain at least one non-empty string`)
+	case r.Draft.Body == "":
+		return errors.New(`"draft.body" must be a non-empty string`)
+	}
+	return nil
+}
+
+// schemaDescriptions are the descriptions of the schemas, by schema name and
+// prompt language (see prompts.Rendered.Language).
+var schemaDescriptions = map[string]map[string]string{
+	"suggest_purposes": {
+		"it": "Restituisce il titolo sintetico del post e gli scopi proposti per un commento.",
+		"en": "Returns the short title of the post and the purposes proposed for a comment.",
+	},
+	"write_comment": {
+		"it": "Restituisce il commento nella lingua originale e la sua traduzione nella lingua dell'utente.",
+		"en": "Returns the comment in the original language and its translation into the user's language.",
+	},
+	"write_comment_variants": {
+		"it": "Restituisce più varianti del commento, ognuna con un angolo diverso, nella lingua originale e tradotte nella lingua dell'utente.",
+		"en": "Returns several variants of the comment, each with a different angle, in the original language and translated into the user's language.",
+	},
+	"draft_replies": {
+		"it": "Restituisce una risposta per ogni commento indicato, nella lingua del commento e tradotta nella lingua dell'utente.",
+		"en": "Returns a reply to each listed comment, in the language of the comment and translated into the user's language.",
+	},
+	"draft_post": {
+		"it": "Restituisce gli angoli proposti per un post e la bozza con le varianti di gancio.",
+		"en": "Returns the angles proposed for a post and the draft with its hook variants.",
+	},
+}
+
+// localized returns a copy of schema described in lang, or in Italian.
+func localized(schema *llm.Schema, lang string) *llm.Schema {
+	descriptions := schemaDescriptions[schema.Name]
+	out := *schema
+	if out.Description = descriptions[lang]; out.Description == "" {
+		out.Description = descriptions["it"]
+	}
+	return &out
+}
+
+// repairMessages ask the model to fix an invalid answer, by prompt language,
+// with the problem and the schema name.
+var repairMessages = map[string]string{
+	"it": "La risposta precedente non è valida (%v). Rispondi di nuovo SOLO con un oggetto JSON conforme allo schema %q, senza altro testo.",
+	"en": "The previous answer is not valid (%v). Answer again with ONLY a JSON object matching the %q schema, with no other text.",
+}
+
+var purposesSchema = &llm.Schema{
+	Name: "suggest_purposes",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title":    map[string]any{"type": "string"},
+			"purposes": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required":             []string{"title", "purposes"},
+		"additionalProperties": false,
+	},
+}
+
+var commentSchema = &llm.Schema{
+	Name: "write_comment",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"comment":     map[string]any{"type": "string"},
+			"translation": map[string]any{"type": "string"},
+		},
+		"required":             []string{"comment", "translation"},
+		"additionalProperties": false,
+	},
+}
+
+var variantsSchema = &llm.Schema{
+	Name: "write_comment_variants",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"variants": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"angle":       map[string]any{"type": "string"},
+						"comment":     map[string]any{"type": "string"},
+						"translation": map[string]any{"type": "string"},
+					},
+					"required":             []string{"angle", "comment", "translation"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"variants"},
+		"additionalProperties": false,
+	},
+}
+
+var repliesSchema = &llm.Schema{
+	Name: "draft_replies",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"replies": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"index":       map[string]any{"type": "integer"},
+						"reply":       map[string]any{"type": "string"},
+						"translation": map[string]any{"type": "string"},
+					},
+					"required":             []string{"index", "reply", "translation"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"replies"},
+		"additionalProperties": false,
+	},
+}
+
+var postDraftSchema = &llm.Schema{
+	Name: "draft_post",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"angles": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"title":     map[string]any{"type": "string"},
+						"rationale": map[string]any{"type": "string"},
+					},
+					"required":             []string{"title", "rationale"},
+					"additionalProperties": false,
+				},
+			},
+			"draft": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"angle":    map[string]any{"type": "string"},
+					"hooks":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"body":     map[string]any{"type": "string"},
+					"hashtags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+				"required":             []string{"angle", "hooks", "body", "hashtags"},
+				"additionalProperties": false,
+			},
+		},
+		"required":             []string{"angles", "draft"},
+		"additionalProperties": false,
+	},
+}
+
+// result is implemented by the typed AI outputs; validate may also normalize.
+type result[T any] interface {
+	*T
+	validate() error
+}
+
+// aiError carries one of the Code* constants up to the handler.
+type aiError struct {
+	Code string
+	Err  error
+}
+
+func (e *aiError) Error() string { return e.Code + ": " + e.Err.Error() }
+func (e *aiError) Unwrap() error { return e.Err }
+
+// completeStructured asks the provider for a schema-constrained answer and
+// decodes it into T. Output that does not parse or validate gets one retry
+// with a repair prompt quoting the problem; a second failure is CodeInvalidOutput.
+// The schema description and the repair prompt are in lang, the language of
+// the prompt.
+func completeStructured[T any, PT result[T]](ctx context.Context, provider llm.Provider, req llm.Request, schema *llm.Schema, lang string) (T, llm.Response, error) {
+	req.Schema = localized(schema, lang)
+
+	resp, err := provider.Complete(ctx, req)
+	if err != nil {
+		var zero T
+		return zero, resp, &aiError{Code: CodeProviderError, Err: err}
+	}
+	out, parseErr := parseStructured[T, PT](resp.Text)
+	if parseErr == nil {
+		return out, resp, nil
+	}
+
+	logger.Warn("invalid structured output, retrying", "schema", schema.Name, "err", parseErr)
+	return repairStructured[T, PT](ctx, provider, req, resp.Text, parseErr, lang)
+}
+
+// repairStructured is the single retry: the invalid answer goes back to the
+// model as its own turn, followed by what was wrong with it.
+func repairStructured[T any, PT result[T]](ctx context.Context, provider llm.Provider, req llm.Request, invalid string, cause error, lang string) (T, llm.Response, error) {
+	var zero T
+	message, ok := repairMessages[lang]
+	if !ok {
+		message = repairMessages["it"]
+	}
+	req.Messages = append(append([]llm.Message(nil), req.Messages...),
+		llm.Message{Role: "assistant", Content: invalid},
+		llm.Message{Role: "user", Content: fmt.Sprintf(message, cause, req.Schema.Name)},
+	)
+
+	resp, err := provider.Complete(ctx, req)
+	if err != nil {
+		return zero, resp, &aiError{Code: CodeProviderError, Err: err}
+	}
+	out, err := parseStructured[T, PT](resp.Text)
+	if err != nil {
+		return zero, resp, &aiError{Code: CodeInvalidOutput, Err: err}
+	}
+	return out, resp, nil
+}
+
+func parseStructured[T any, PT result[T]](text string) (T, error) {
+	var out T
+	if err := llm.DecodeObject(text, &out); err != nil {
+		return out, err
+	}
+	if err := PT(&out).validate(); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// aiErrorResponse maps a generation error to a 502 with its code.
+func aiErrorResponse(c *fiber.Ctx, lang string, err error) error {
+	return c.Status(502).JSON(aiErrorBody(lang, err))
+}
+
+func aiErrorBody(lang string, err error) fiber.Map {
+	code := CodeProviderError
+	var aerr *aiError
+	if errors.As(err, &aerr) {
+		code = aerr.Code
+	}
+	if code == CodeInvalidOutput {
+		return fiber.Map{"error": i18n.T(lang, "error.ai_invalid_output"), "code": code}
+	}
+	// The raw provider error (with its response body) is logged by the caller only.
+	return fiber.Map{"error": i18n.T(lang, "error."+llm.ErrorKind(err)), "code": code}
+}
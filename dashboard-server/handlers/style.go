@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"dashboard-server/database"
+	"dashboard-server/i18n"
+	"dashboard-server/logger"
+	"dashboard-server/middleware"
+	"dashboard-server/prompts"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// StyleProfile is how the user writes, injected into the AI prompts. It is
+// stored as style_* properties on the User node, so it goes away with the account.
+type StyleProfile struct {
+	Role          string   `json:"role"`
+	Expertise     []string `json:"expertise"`
+	Tone          string   `json:"tone"`
+	BannedPhrases []string `json:"banned_phrases"`
+	Samples       []string `json:"samples"` // past comments written by the user
+}
+
+// Limits keep the profile from blowing up every prompt.
+const (
+	styleMaxText       = 300
+	styleMaxSample     = 1000
+	styleMaxSamples    = 5
+	styleMaxListItems  = 20
+	styleMaxPhraseText = 100
+)
+
+// normalize trims and caps every field.
+func (p *StyleProfile) normalize() {
+	p.Role = truncateRunes(strings.TrimSpace(p.Role), styleMaxText)
+	p.Tone = truncateRunes(strings.TrimSpace(p.Tone), styleMaxText)
+	p.Expertise = cleanList(p.Expertise, styleMaxListItems, styleMaxPhraseText)
+	p.BannedPhrases = cleanList(p.BannedPhrases, styleMaxListItems, styleMaxPhraseText)
+	p.Samples = cleanList(p.Samples, styleMaxSamples, styleMaxSample)
+}
+
+func (p StyleProfile) empty() bool {
+	return p.Role == "" && p.Tone == "" && len(p.Expertise) == 0 && len(p.BannedPhrases) == 0 && len(p.Samples) == 0
+}
+
+// professionalContext summarizes role and expertise for the purposes prompt,
+// when the plugin did not send its own context.
+func (p StyleProfile) professionalContext() string {
+	var parts []string
+	if p.Role != "" {
+		parts = append(parts, p.Role)
+	}
+	if len(p.Expertise) > 0 {
+		parts = append(parts, "competenze: "+strings.Join(p.Expertise, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (p *StyleProfile) prompt() *prompts.StyleProfile {
+	if p == nil || p.empty() {
+		return nil
+	}
+	return &prompts.StyleProfile{
+		Role:          p.Role,
+		Expertise:     p.Expertise,
+		Tone:          p.Tone,
+		BannedPhrases: p.BannedPhrases,
+		Samples:       p.Samples,
+	}
+}
+
+// GetStyleProfile handles GET /api/user/style-profile
+func GetStyleProfile(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	profile, err := loadStyleProfile(userID)
+	if err != nil {
+		logger.Error("style profile load failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	if profile == nil {
+		profile = &StyleProfile{}
+	}
+	return c.JSON(profile)
+}
+
+// UpdateStyleProfile handles PUT /api/user/style-profile (replaces the whole profile)
+func UpdateStyleProfile(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+
+	var profile StyleProfile
+	if err := c.BodyParser(&profile); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.invalid_body")})
+	}
+	profile.normalize()
+
+	if err := saveStyleProfile(userID, profile); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.user_not_found")})
+		}
+		logger.Error("style profile save failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	return c.JSON(profile)
+}
+
+// DeleteStyleProfile handles DELETE /api/user/style-profile
+func DeleteStyleProfile(c *fiber.Ctx) error {
+	userID := middleware.UserID(c)
+	if err := saveStyleProfile(userID, StyleProfile{}); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": i18n.T(userLanguage(c, userID), "error.user_not_found")})
+		}
+		logger.Error("style profile delete failed", "err", err, "user_id", userID)
+		return c.Status(500).JSON(fiber.Map{"error": "Neo4j query failed"})
+	}
+	return c.JSON(StyleProfile{})
+}
+
+// userStyleProfile is loadStyleProfile for the AI handlers: on errors they
+// generate without personalization.
+func userStyleProfile(userID int64) *StyleProfile {
+	profile, err := loadStyleProfile(userID)
+	if err != nil {
+		logger.Warn("failed to load style profile", "err", err, "user_id", userID)
+		return nil
+	}
+	return profile
+}
+
+// loadStyleProfile returns nil when the user has no profile.
+func loadStyleProfile(userID int64) (*StyleProfile, error) {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (u:User {id: $userId})
+		RETURN u.style_role AS role, u.style_expertise AS expertise, u.style_tone AS tone,
+		       u.style_banned_phrases AS banned_phrases, u.style_samples AS samples
+		`
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID})
+		if err != nil {
+			return nil, err
+		}
+		if !rec.Next(ctx) {
+			return (*StyleProfile)(nil), rec.Err()
+		}
+		r := rec.Record()
+		profile := &StyleProfile{
+			Role:          database.RecordString(r, "role"),
+			Expertise:     database.RecordStrings(r, "expertise"),
+			Tone:          database.RecordString(r, "tone"),
+			BannedPhrases: database.RecordStrings(r, "banned_phrases"),
+			Samples:       database.RecordStrings(r, "samples"),
+		}
+		if profile.empty() {
+			return (*StyleProfile)(nil), nil
+		}
+		return profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*StyleProfile), nil
+}
+
+// saveStyleProfile stores the profile; empty fields remove the property.
+func saveStyleProfile(userID int64, p StyleProfile) error {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+		MATCH (u:User {id: $userId})
+		SET u.style_role = $role, u.style_expertise = $expertise, u.style_tone = $tone,
+		    u.style_banned_phrases = $bannedPhrases, u.style_samples = $samples,
+		    u.style_updated_at = CASE WHEN $empty THEN null ELSE datetime() END
+		RETURN u.id
+		`
+		return nil, runUserWrite(ctx, tx, query, map[string]any{
+			"userId":        userID,
+			"role":          database.NullIfEmpty(p.Role),
+			"expertise":     database.NullIfEmptyList(p.Expertise),
+			"tone":          database.NullIfEmpty(p.Tone),
+			"bannedPhrases": database.NullIfEmptyList(p.BannedPhrases),
+			"samples":       database.NullIfEmptyList(p.Samples),
+			"empty":         p.empty(),
+		})
+	})
+	return err
+}
+
+func cleanList(items []string, maxItems, maxLen int) []string {
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, truncateRunes(item, maxLen))
+		}
+		if len(out) == maxItems {
+			break
+		}
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"dashboard-server/database"
 	"dashboard-server/logger"
+	"dashboard-server/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -30,7 +30,7 @@ const (
 
 // GetUsage handles GET /api/user/usage
 func GetUsage(c *fiber.Ctx) error {
-	userID := fmt.Sprint(c.Locals("user_id"))
+	userID := middleware.UserID(c)
 	// In a real scenario, tier would come from the session/JWT or a DB call.
 	// Since dashboard-server doesn't have the User PG model, we can extract it from locals
 	// assuming the AuthRequired middleware was updated or we fetch it here.
@@ -45,18 +45,18 @@ func GetUsage(c *fiber.Ctx) error {
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// 1. Count comments generated today
+		// 1. Count AI sessions used today (comments, replies and posts; variants and regenerations count once)
 		// 2. Count total Person nodes discovered (as a proxy for graph maturity)
 		query := `
 		MATCH (u:User {id: $userId})
-		OPTIONAL MATCH (u)-[a:ACTION]->(p:Post)
-		WHERE a.type = 'comment_generated' AND date(datetime(a.timestamp)) = date()
-		WITH count(a) AS comments_today
+		OPTIONAL MATCH (u)-[:GENERATED]->(g:Generation)
+		WHERE g.type IN $types AND date(g.created_at) = date() AND NOT coalesce(g.byok, false)
+		WITH count(DISTINCT coalesce(g.session_id, g.id)) AS comments_today
 		
 		MATCH (p:Person)
 		RETURN comments_today, count(p) AS nodes_count
 		`
-		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID})
+		rec, err := tx.Run(ctx, query, map[string]any{"userId": userID, "types": meteredGenerationTypes})
 		if err != nil {
 			return nil, err
 		}
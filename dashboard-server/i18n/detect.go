@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Function words are frequent and distinctive enough to tell apart the
+// supported languages on a few sentences of LinkedIn text.
+var stopwords = map[string][]string{
+	"it": {"il", "lo", "la", "gli", "le", "di", "che", "non", "per", "una", "sono", "con", "del", "della", "nel", "anche", "questo", "più", "ma", "come", "è"},
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "it", "with", "this", "are", "on", "you", "we", "be", "have", "not", "but", "our"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "es", "por", "para", "una", "con", "no", "del", "lo", "como", "más", "pero", "muy"},
+	"fr": {"le", "la", "les", "des", "de", "et", "est", "que", "une", "pour", "dans", "pas", "sur", "avec", "qui", "nous", "vous", "du", "au", "ce"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "für", "auf", "sich", "wir", "ich", "auch", "es", "dem", "von"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := map[string]map[string]bool{}
+	for lang, words := range stopwords {
+		sets[lang] = map[string]bool{}
+		for _, w := range words {
+			sets[lang][w] = true
+		}
+	}
+	return sets
+}()
+
+// Detect guesses the language of a text among the supported ones by counting
+// function words. It returns "" when the text is too short or ambiguous.
+func Detect(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < 5 {
+		return ""
+	}
+
+	scores := map[string]int{}
+	for _, w := range words {
+		for lang, set := range stopwordSets {
+			if set[w] {
+				scores[lang]++
+			}
+		}
+	}
+
+	best, bestScore, second := "", 0, 0
+	for _, l := range languages {
+		s := scores[l.Code]
+		switch {
+		case s > bestScore:
+			best, bestScore, second = l.Code, s, bestScore
+		case s > second:
+			second = s
+		}
+	}
+	// At least two hits and a clear margin over the runner-up.
+	if bestScore < 2 || bestScore*4 < second*5 {
+		return ""
+	}
+	return best
+}
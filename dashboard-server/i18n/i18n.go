@@ -0,0 +1,127 @@
+// Package i18n holds the supported user languages, the message catalogs for
+// user-facing API errors and a lightweight language detector for post text.
+//
+// Catalogs are flat JSON files, locales/<code>.json, mapping a message key to
+// a fmt format string. A key missing from a catalog falls back to Default.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Default is the language of users without a preference: the product started Italian-only.
+const Default = "it"
+
+// Language is a supported language. PromptNames is how the prompts refer to
+// it, by the language the prompt is written in: "traduzione in inglese",
+// "translation into English".
+type Language struct {
+	Code        string            `json:"code"`
+	Name        string            `json:"name"`
+	PromptNames map[string]string `json:"-"`
+}
+
+var languages = []Language{
+	{Code: "it", Name: "Italiano", PromptNames: map[string]string{"it": "italiano", "en": "Italian"}},
+	{Code: "en", Name: "English", PromptNames: map[string]string{"it": "inglese", "en": "English"}},
+	{Code: "es", Name: "Español", PromptNames: map[string]string{"it": "spagnolo", "en": "Spanish"}},
+	{Code: "fr", Name: "Français", PromptNames: map[string]string{"it": "francese", "en": "French"}},
+	{Code: "de", Name: "Deutsch", PromptNames: map[string]string{"it": "tedesco", "en": "German"}},
+}
+
+// PromptName returns how a prompt written in promptLang names l, falling
+// back to the English name.
+func (l Language) PromptName(promptLang string) string {
+	if name, ok := l.PromptNames[promptLang]; ok {
+		return name
+	}
+	return l.PromptNames["en"]
+}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	for _, l := range languages {
+		raw, err := localeFiles.ReadFile(path.Join("locales", l.Code+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", l.Code, err))
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(raw, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", l.Code, err))
+		}
+		catalogs[l.Code] = catalog
+	}
+}
+
+// Languages lists the supported languages, for the settings UI.
+func Languages() []Language {
+	return append([]Language(nil), languages...)
+}
+
+// Codes lists the supported language codes.
+func Codes() []string {
+	codes := make([]string, len(languages))
+	for i, l := range languages {
+		codes[i] = l.Code
+	}
+	return codes
+}
+
+// Supported reports whether code is a supported language.
+func Supported(code string) bool {
+	_, ok := catalogs[code]
+	return ok
+}
+
+// Get returns a supported language, or Default.
+func Get(code string) Language {
+	for _, l := range languages {
+		if l.Code == code {
+			return l
+		}
+	}
+	return languages[0]
+}
+
+// T translates a message key, formatting args into it.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = catalogs[Default][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Normalize turns a tag like "en-US" or "EN" into a code ("en").
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// FromAcceptLanguage returns the first supported language in an
+// Accept-Language header, or "".
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if code := Normalize(tag); Supported(code) {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+// Package keyvault stores users' own LLM provider API keys with envelope
+// encryption: every key is encrypted with its own random data key
+// (AES-256-GCM), and the data key is wrapped with the server master key
+// (BYOK_MASTER_KEY). Only wrapped material ever reaches Redis, and rotating
+// the master key only requires re-wrapping data keys.
+package keyvault
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"dashboard-server/database"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyPrefix is the Redis hash holding a user's key ("byok:<user_id>").
+// auth-service deletes it when the account is purged.
+const KeyPrefix = "byok:"
+
+var ErrNotFound = errors.New("no API key stored")
+
+// StoredKey is a user's key once decrypted.
+type StoredKey struct {
+	Provider  string // "anthropic" | "openai"
+	APIKey    string
+	BaseURL   string // openai-compatible endpoints only
+	Last4     string
+	CreatedAt time.Time
+}
+
+// masterKey reads BYOK_MASTER_KEY (base64, 32 bytes) and BYOK_MASTER_KEY_ID.
+func masterKey() ([]byte, string, error) {
+	raw := os.Getenv("BYOK_MASTER_KEY")
+	if raw == "" {
+		return nil, "", fmt.Errorf("BYOK_MASTER_KEY non configurata sul server")
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(key) != 32 {
+		return nil, "", fmt.Errorf("BYOK_MASTER_KEY must be 32 bytes, base64 encoded")
+	}
+	id := os.Getenv("BYOK_MASTER_KEY_ID")
+	if id == "" {
+		id = "v1"
+	}
+	return key, id, nil
+}
+
+func seal(key, plaintext []byte) (ciphertext, nonce []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return gcm.Seal(nil, nonce, plaintext, nil), nonce, nil
+}
+
+func open(key, ciphertext, nonce []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// Save encrypts and stores the user's key, replacing any previous one.
+func Save(ctx context.Context, userID int64, k StoredKey) error {
+	kek, kekID, err := masterKey()
+	if err != nil {
+		return err
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return err
+	}
+	ciphertext, nonce, err := seal(dek, []byte(k.APIKey))
+	if err != nil {
+		return err
+	}
+	wrappedKey, keyNonce, err := seal(kek, dek)
+	if err != nil {
+		return err
+	}
+
+	last4 := k.APIKey
+	if len(last4) > 4 {
+		last4 = last4[len(last4)-4:]
+	}
+
+	b64 := base64.StdEncoding.EncodeToString
+	redisKey := KeyPrefix + strconv.FormatInt(userID, 10)
+	pipe := database.RedisClient.TxPipeline()
+	pipe.Del(ctx, redisKey)
+	pipe.HSet(ctx, redisKey, map[string]any{
+		"provider":    k.Provider,
+		"base_url":    k.BaseURL,
+		"ciphertext":  b64(ciphertext),
+		"nonce":       b64(nonce),
+		"wrapped_key": b64(wrappedKey),
+		"key_nonce":   b64(keyNonce),
+		"kek_id":      kekID,
+		"last4":       last4,
+		"created_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Load returns the user's decrypted key, or ErrNotFound.
+func Load(ctx context.Context, userID int64) (StoredKey, error) {
+	fields, err := database.RedisClient.HGetAll(ctx, KeyPrefix+strconv.FormatInt(userID, 10)).Result()
+	if err != nil && err != redis.Nil {
+		return StoredKey{}, err
+	}
+	if len(fields) == 0 {
+		return StoredKey{}, ErrNotFound
+	}
+
+	kek, kekID, err := masterKey()
+	if err != nil {
+		return StoredKey{}, err
+	}
+	if fields["kek_id"] != kekID {
+		return StoredKey{}, fmt.Errorf("API key wrapped with unknown master key %q", fields["kek_id"])
+	}
+
+	decode := func(name string) []byte {
+		b, _ := base64.StdEncoding.DecodeString(fields[name])
+		return b
+	}
+	dek, err := open(kek, decode("wrapped_key"), decode("key_nonce"))
+	if err != nil {
+		return StoredKey{}, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	apiKey, err := open(dek, decode("ciphertext"), decode("nonce"))
+	if err != nil {
+		return StoredKey{}, fmt.Errorf("failed to decrypt API key: %w", err)
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, fields["created_at"])
+	return StoredKey{
+		Provider:  fields["provider"],
+		APIKey:    string(apiKey),
+		BaseURL:   fields["base_url"],
+		Last4:     fields["last4"],
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// Delete removes the user's key. Deleting a missing key is not an error.
+func Delete(ctx context.Context, userID int64) error {
+	return database.RedisClient.Del(ctx, KeyPrefix+strconv.FormatInt(userID, 10)).Err()
+}
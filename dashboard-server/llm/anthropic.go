@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	AnthropicVersion = "2023-06-01"
+	AnthropicURL     = "https://api.anthropic.com/v1/messages"
+	AnthropicModel   = "claude-sonnet-4-5"
+)
+
+type Anthropic struct {
+	APIKey   string
+	URL      string
+	Settings Settings
+	Client   *Client // nil: DefaultClient
+}
+
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "tool": the model must call the named tool
+	Name string `json:"name"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"` // tool_use
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// anthropicStreamEvent covers the Messages API stream events we care about.
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *anthropicResponse `json:"message,omitempty"` // message_start
+	Delta   struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage *struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage,omitempty"` // message_delta
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (a *Anthropic) Name() string { return "anthropic" }
+
+func (a *Anthropic) client() *Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	return DefaultClient
+}
+
+func (a *Anthropic) newRequest(ctx context.Context, req Request, stream bool) (*http.Request, error) {
+	if a.APIKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY non configurata sul server")
+	}
+
+	model, maxTokens, temperature := a.Settings.apply(req)
+	payload := anthropicRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		System:      req.System,
+		Messages:    req.Messages,
+		Temperature: temperature,
+		Stream:      stream,
+	}
+	// Structured output: a single tool the model is forced to call, whose
+	// input is the object we want.
+	if req.Schema != nil && !stream {
+		payload.Tools = []anthropicTool{{
+			Name:        req.Schema.Name,
+			Description: req.Schema.Description,
+			InputSchema: req.Schema.Parameters,
+		}}
+		payload.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.Schema.Name}
+	}
+	body, _ := json.Marshal(payload)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", a.APIKey)
+	httpReq.Header.Set("anthropic-version", AnthropicVersion)
+	return httpReq, nil
+}
+
+func (a *Anthropic) Complete(ctx context.Context, req Request) (Response, error) {
+	resp, err := a.client().Do(ctx, func() (*http.Request, error) { return a.newRequest(ctx, req, false) })
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(a.Name(), resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var anthropicResp anthropicResponse
+	if err := json.Unmarshal(body, &anthropicResp); err != nil {
+		return Response{}, err
+	}
+
+	out := Response{
+		Model:        anthropicResp.Model,
+		InputTokens:  anthropicResp.Usage.InputTokens,
+		OutputTokens: anthropicResp.Usage.OutputTokens,
+	}
+	for _, block := range anthropicResp.Content {
+		switch {
+		case block.Type == "tool_use" && len(block.Input) > 0:
+			out.Text = string(block.Input)
+			return out, nil
+		case block.Type == "text" && req.Schema == nil:
+			out.Text = block.Text
+			return out, nil
+		}
+	}
+
+	return Response{}, fmt.Errorf("Risposta Anthropic vuota o non valida")
+}
+
+func (a *Anthropic) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	resp, err := a.client().Do(ctx, func() (*http.Request, error) { return a.newRequest(ctx, req, true) })
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(a.Name(), resp)
+	}
+
+	var out Response
+	var full strings.Builder
+	err = scanSSE(resp.Body, func(data string) (bool, error) {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return false, nil
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				out.Model = ev.Message.Model
+				out.InputTokens = ev.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" {
+				full.WriteString(ev.Delta.Text)
+				return false, onDelta(ev.Delta.Text)
+			}
+		case "message_delta":
+			if ev.Usage != nil {
+				out.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "error":
+			if ev.Error != nil {
+				return true, fmt.Errorf("Anthropic stream error: %s", ev.Error.Message)
+			}
+			return true, fmt.Errorf("Anthropic stream error")
+		case "message_stop":
+			return true, nil
+		}
+		return false, nil
+	})
+	out.Text = full.String()
+	return out, err
+}
+
+// scanSSE feeds the data of each Server-Sent Event line to handle until it
+// reports done or returns an error.
+func scanSSE(body io.Reader, handle func(data string) (done bool, err error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		done, err := handle(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		if err != nil || done {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBaseURLNotAllowed is returned for a user-supplied base URL (bring your
+// own key) that is not a public https endpoint, or not in
+// LLM_BYOK_BASE_URL_HOSTS when that is set.
+var ErrBaseURLNotAllowed = errors.New("base URL not allowed")
+
+// BYOKBaseURLHosts lists the hosts a user's own OpenAI-compatible key may be
+// sent to (LLM_BYOK_BASE_URL_HOSTS, comma-separated). Empty: any host that
+// resolves to public addresses only.
+var BYOKBaseURLHosts []string
+
+// CheckBaseURL validates a user-supplied base URL before any call: https, no
+// credentials, a host in BYOKBaseURLHosts if set, and no literal private
+// address. Hostnames are checked again on every connection, after DNS
+// resolution, by the dialer of byokClient.
+func CheckBaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: must be an https URL", ErrBaseURLNotAllowed)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(BYOKBaseURLHosts) > 0 && !slices.Contains(BYOKBaseURLHosts, host) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrBaseURLNotAllowed, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrBaseURLNotAllowed, host)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return fmt.Errorf("%w: %s is not a public host", ErrBaseURLNotAllowed, host)
+	}
+	return nil
+}
+
+// Shared address space (RFC 6598), used by carrier-grade NAT and some clusters.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether addr is a globally routable unicast address.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// byokClient sends the calls to user-supplied base URLs. Its dialer refuses
+// non-public addresses once the name is resolved, so a hostname pointing (or
+// rebinding) to an internal service is never reached, and it ignores the
+// proxy environment, which would dial on its behalf.
+var byokClient = &Client{
+	HTTP: &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: 10 * time.Second,
+				Control: func(network, address string, _ syscall.RawConn) error {
+					addrPort, err := netip.ParseAddrPort(address)
+					if err != nil || !publicAddr(addrPort.Addr()) {
+						return fmt.Errorf("%w: %s is not a public address", ErrBaseURLNotAllowed, address)
+					}
+					return nil
+				},
+			}).DialContext,
+			MaxIdleConnsPerHost:   5,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 45 * time.Second,
+		},
+		// A redirect could point anywhere: the dialer still checks it, but
+		// there is no reason for a completion endpoint to redirect.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	},
+	MaxRetries: DefaultClient.MaxRetries,
+	BaseDelay:  DefaultClient.BaseDelay,
+	MaxDelay:   DefaultClient.MaxDelay,
+}
@@ -0,0 +1,299 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("LLM provider circuit open")
+
+// RequestTimeout bounds a whole AI call, retries included. Handlers derive
+// their context from it (LLM_TIMEOUT_SECONDS).
+var RequestTimeout = 60 * time.Second
+
+// APIError is a non-2xx answer from a provider. Body is for the logs only:
+// it can contain request details and must not reach the end user (see ErrorKind).
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error: %d - %s", e.Provider, e.StatusCode, e.Body)
+}
+
+func newAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &APIError{Provider: provider, StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// Client sends provider HTTP calls with retries on overload (exponential
+// backoff with full jitter, honoring Retry-After) and a circuit breaker per
+// upstream host and credential (see breakerKey).
+type Client struct {
+	HTTP       *http.Client
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultClient is used by providers without their own Client. Init tunes it
+// from LLM_MAX_RETRIES.
+var DefaultClient = &Client{
+	HTTP: &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost:   20,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 45 * time.Second,
+		},
+	},
+	MaxRetries: 2,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   8 * time.Second,
+}
+
+// Do sends the request built by build, rebuilding it for every attempt (the
+// body can only be read once). The caller owns the response body. A response
+// with a retryable status is returned as-is once retries are exhausted.
+func (c *Client) Do(ctx context.Context, build func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		httpReq, err := build()
+		if err != nil {
+			return nil, err
+		}
+
+		b := breakerFor(breakerKey(httpReq))
+		if !b.allow() {
+			return nil, ErrCircuitOpen
+		}
+
+		resp, err := c.HTTP.Do(httpReq)
+		if err != nil && ctx.Err() != nil {
+			// Our own deadline or cancellation says nothing about the provider.
+			b.release()
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrBaseURLNotAllowed) {
+			// Refused by byokClient's dialer: not the provider's fault, and final.
+			b.release()
+			return nil, err
+		}
+		switch {
+		case err == nil && !retryableStatus(resp.StatusCode):
+			// Answered, even if with an auth error: the provider is up
+			b.record(true)
+			return resp, nil
+		case err == nil && resp.StatusCode == http.StatusTooManyRequests:
+			// The key's quota, not an outage: retried, but not counted
+			b.release()
+		default:
+			b.record(false)
+		}
+
+		wait := c.backoff(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp); ok {
+				wait = ra
+			}
+		}
+		if attempt >= c.MaxRetries || !fitsDeadline(ctx, wait) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff is full jitter: a random delay in [0, min(MaxDelay, BaseDelay*2^attempt)].
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > c.MaxDelay {
+		ceiling = c.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryableStatus: rate limiting, overload (Anthropic's 529) and gateway errors.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// fitsDeadline reports whether waiting still leaves time for another attempt.
+func fitsDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > wait
+}
+
+// Circuit breaker settings (LLM_BREAKER_THRESHOLD, LLM_BREAKER_COOLDOWN_SECONDS).
+var (
+	BreakerThreshold = 5
+	BreakerCooldown  = 30 * time.Second
+)
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// maxBreakers bounds the breakers kept, one per host and API key: past it,
+// the closed ones are dropped.
+const maxBreakers = 10000
+
+// breakerKey identifies the circuit of a request: its host and a hash of its
+// API key, so a user's own key (bring-your-own-key) failing in a loop opens
+// their circuit only, not the managed key's.
+func breakerKey(req *http.Request) string {
+	credential := req.Header.Get("x-api-key") + req.Header.Get("Authorization")
+	sum := sha256.Sum256([]byte(credential))
+	return req.URL.Host + "/" + hex.EncodeToString(sum[:8])
+}
+
+func breakerFor(key string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[key]
+	if !ok {
+		if len(breakers) >= maxBreakers {
+			for k, other := range breakers {
+				if other.closed() {
+					delete(breakers, k)
+				}
+			}
+		}
+		b = &breaker{}
+		breakers[key] = b
+	}
+	return b
+}
+
+// breaker opens after BreakerThreshold consecutive failures and fails fast
+// for BreakerCooldown; then a single probe call decides whether it closes
+// again or stays open for another cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < BreakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= BreakerThreshold {
+		b.openUntil = time.Now().Add(BreakerCooldown)
+	}
+}
+
+func (b *breaker) closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures == 0 && !b.probing
+}
+
+// release gives back a probe that ended without an answer from the provider.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Error kinds, for user-facing messages: the error itself, with the
+// provider's response body, goes to the logs only.
+const (
+	ErrorUnavailable = "ai_unavailable"
+	ErrorCircuitOpen = "ai_circuit_open"
+	ErrorTimeout     = "ai_timeout"
+	ErrorCanceled    = "ai_canceled"
+	ErrorInvalidKey  = "ai_invalid_key"
+	ErrorOverloaded  = "ai_overloaded"
+	ErrorBaseURL     = "invalid_base_url"
+)
+
+// ErrorKind classifies a provider error into one of the Error* kinds.
+func ErrorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.Is(err, ErrBaseURLNotAllowed):
+		return ErrorBaseURL
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorInvalidKey
+		case http.StatusTooManyRequests, 529:
+			return ErrorOverloaded
+		}
+	}
+	return ErrorUnavailable
+}
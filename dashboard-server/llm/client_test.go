@@ -0,0 +1,351 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upstream is an httptest fake of a provider: it answers with statuses in
+// order (the last one repeats) and counts the calls.
+type upstream struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newUpstream(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, call int)) *upstream {
+	t.Helper()
+	u := &upstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, int(u.calls.Add(1)))
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func statuses(codes ...int) func(http.ResponseWriter, *http.Request, int) {
+	return func(w http.ResponseWriter, r *http.Request, call int) {
+		w.WriteHeader(codes[min(call, len(codes))-1])
+	}
+}
+
+func testClient(retries int) *Client {
+	return &Client{HTTP: &http.Client{}, MaxRetries: retries, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+// do sends a POST to u with key as credential.
+func do(t *testing.T, ctx context.Context, c *Client, u *upstream, key string) (*http.Response, error) {
+	t.Helper()
+	resp, err := c.Do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", u.URL, strings.NewReader("{}"))
+		if err == nil {
+			req.Header.Set("x-api-key", key)
+		}
+		return req, err
+	})
+	if resp != nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func withBreaker(t *testing.T, threshold int, cooldown time.Duration) {
+	t.Helper()
+	oldThreshold, oldCooldown := BreakerThreshold, BreakerCooldown
+	BreakerThreshold, BreakerCooldown = threshold, cooldown
+	t.Cleanup(func() { BreakerThreshold, BreakerCooldown = oldThreshold, oldCooldown })
+}
+
+func TestDoRetriesOverload(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		retries   int
+		wantCode  int
+		wantCalls int32
+	}{
+		{"429 then ok", []int{429, 200}, 2, 200, 2},
+		{"529 then ok", []int{529, 200}, 2, 200, 2},
+		{"429 and 529 then ok", []int{429, 529, 200}, 2, 200, 3},
+		{"exhausted returns the last answer", []int{529}, 2, 529, 3},
+		{"no retries", []int{503, 200}, 0, 503, 1},
+		{"client errors are not retried", []int{400, 200}, 2, 400, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream(t, statuses(tt.codes...))
+			resp, err := do(t, context.Background(), testClient(tt.retries), u, "key-"+tt.name)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if got := u.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoHonorsRetryAfter(t *testing.T) {
+	u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		if call == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	start := time.Now()
+	resp, err := do(t, context.Background(), testClient(1), u, "retry-after")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Do = %v, %v; want 200", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s of Retry-After", elapsed)
+	}
+}
+
+func TestDoSkipsRetryPastDeadline(t *testing.T) {
+	u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := do(t, ctx, testClient(3), u, "deadline")
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Do = %v, %v; want the 503 at once", resp, err)
+	}
+	if got := u.calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1: a 30s Retry-After does not fit a 1s deadline", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.header)
+		got, ok := retryAfter(resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if got, ok := retryAfter(resp); !ok || got <= 0 || got > time.Minute {
+		t.Errorf("retryAfter(date in a minute) = %v, %v", got, ok)
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	c := &Client{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 70; attempt++ {
+		ceiling := min(c.BaseDelay<<min(attempt, 20), c.MaxDelay)
+		for i := 0; i < 50; i++ {
+			if d := c.backoff(attempt); d < 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestBreakerOpensAndHalfOpens(t *testing.T) {
+	withBreaker(t, 2, 50*time.Millisecond)
+	var healthy atomic.Bool
+	u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c := testClient(0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if resp, err := do(t, ctx, c, u, "half-open"); err != nil || resp.StatusCode != 500 {
+			t.Fatalf("call %d = %v, %v; want 500", i+1, resp, err)
+		}
+	}
+	if _, err := do(t, ctx, c, u, "half-open"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after %d failures err = %v, want ErrCircuitOpen", BreakerThreshold, err)
+	}
+	if got := u.calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2: an open circuit does not call the provider", got)
+	}
+
+	// After the cooldown a failed probe opens it again...
+	time.Sleep(60 * time.Millisecond)
+	if resp, err := do(t, ctx, c, u, "half-open"); err != nil || resp.StatusCode != 500 {
+		t.Fatalf("probe = %v, %v; want 500", resp, err)
+	}
+	if _, err := do(t, ctx, c, u, "half-open"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after a failed probe err = %v, want ErrCircuitOpen", err)
+	}
+
+	// ...and a successful one closes it
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	for i := 0; i < 3; i++ {
+		if resp, err := do(t, ctx, c, u, "half-open"); err != nil || resp.StatusCode != 200 {
+			t.Fatalf("call %d after recovery = %v, %v; want 200", i+1, resp, err)
+		}
+	}
+}
+
+func TestBreakerAllowsSingleProbe(t *testing.T) {
+	b := &breaker{}
+	withBreaker(t, 1, time.Millisecond)
+	b.record(false)
+	time.Sleep(2 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("allow() = false after the cooldown, want a probe")
+	}
+	if b.allow() {
+		t.Error("allow() = true while probing, want a single probe")
+	}
+	b.release()
+	if !b.allow() {
+		t.Error("allow() = false after release, want a new probe")
+	}
+}
+
+func TestBreakerPerCredential(t *testing.T) {
+	withBreaker(t, 2, time.Minute)
+	u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		if r.Header.Get("x-api-key") == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	c := testClient(0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		do(t, ctx, c, u, "broken")
+	}
+	if _, err := do(t, ctx, c, u, "broken"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("broken key err = %v, want ErrCircuitOpen", err)
+	}
+	if resp, err := do(t, ctx, c, u, "managed"); err != nil || resp.StatusCode != 200 {
+		t.Errorf("another key on the same host = %v, %v; want 200", resp, err)
+	}
+}
+
+func TestBreakerIgnoresQuotaAndAuthErrors(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			withBreaker(t, 2, time.Minute)
+			u := newUpstream(t, statuses(code))
+			c := testClient(0)
+			for i := 0; i < 5; i++ {
+				if _, err := do(t, context.Background(), c, u, "quota"); err != nil {
+					t.Fatalf("call %d err = %v, want the %d answer", i+1, err, code)
+				}
+			}
+			if got := u.calls.Load(); got != 5 {
+				t.Errorf("calls = %d, want 5", got)
+			}
+		})
+	}
+}
+
+func TestDoTimeout(t *testing.T) {
+	withBreaker(t, 1, time.Minute)
+	release := make(chan struct{})
+	u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := do(t, ctx, testClient(2), u, "timeout")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if kind := ErrorKind(err); kind != ErrorTimeout {
+		t.Errorf("ErrorKind = %q, want %q", kind, ErrorTimeout)
+	}
+
+	// Our own deadline is not the provider's failure
+	req := httptest.NewRequest("POST", u.URL, nil)
+	req.Header.Set("x-api-key", "timeout")
+	if !breakerFor(breakerKey(req)).allow() {
+		t.Error("circuit open after a client-side timeout")
+	}
+}
+
+func TestErrorSanitization(t *testing.T) {
+	const secret = "sk-ant-REDACTED"
+	tests := []struct {
+		code int
+		want string
+	}{
+		{http.StatusUnauthorized, ErrorInvalidKey},
+		{http.StatusForbidden, ErrorInvalidKey},
+		{http.StatusTooManyRequests, ErrorOverloaded},
+		{529, ErrorOverloaded},
+		{http.StatusInternalServerError, ErrorUnavailable},
+		{http.StatusBadRequest, ErrorUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			u := newUpstream(t, func(w http.ResponseWriter, r *http.Request, call int) {
+				w.WriteHeader(tt.code)
+				w.Write([]byte(`{"error": {"message": "invalid x-api-key ` + secret + `"}}`))
+			})
+			p := &Anthropic{APIKey: "user-key", URL: u.URL, Settings: Settings{Model: "m", MaxTokens: 10}, Client: testClient(0)}
+			_, err := p.Complete(context.Background(), UserRequest("", "hi"))
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.code {
+				t.Fatalf("err = %v, want an APIError %d", err, tt.code)
+			}
+			if !strings.Contains(apiErr.Body, secret) {
+				t.Error("APIError.Body lost the provider's answer, needed in the logs")
+			}
+			kind := ErrorKind(err)
+			if kind != tt.want {
+				t.Errorf("ErrorKind = %q, want %q", kind, tt.want)
+			}
+			if strings.Contains(kind, secret) {
+				t.Error("ErrorKind leaks the provider's answer")
+			}
+		})
+	}
+
+	if kind := ErrorKind(ErrCircuitOpen); kind != ErrorCircuitOpen {
+		t.Errorf("ErrorKind(ErrCircuitOpen) = %q", kind)
+	}
+	if kind := ErrorKind(context.Canceled); kind != ErrorCanceled {
+		t.Errorf("ErrorKind(context.Canceled) = %q", kind)
+	}
+}
@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	initOnce  sync.Once
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Init builds the providers from the environment:
+//
+//	LLM_PROVIDER                  default provider: anthropic | openai | fake (default: anthropic)
+//	LLM_PROVIDER_TIER_<TIER>      per tier, e.g. LLM_PROVIDER_TIER_FREE=openai
+//	LLM_PROVIDER_<FEATURE>        per feature, e.g. LLM_PROVIDER_PURPOSES=openai (wins over tier)
+//	LLM_<FEATURE>_MAX_TOKENS      per feature token limit, e.g. LLM_COMMENT_MAX_TOKENS=600
+//	LLM_<FEATURE>_TEMPERATURE     per feature temperature
+//
+//	ANTHROPIC_API_KEY, ANTHROPIC_MODEL, ANTHROPIC_MAX_TOKENS, ANTHROPIC_TEMPERATURE
+//	OPENAI_API_KEY, OPENAI_BASE_URL, OPENAI_MODEL, OPENAI_MAX_TOKENS, OPENAI_TEMPERATURE
+//
+//	LLM_TIMEOUT_SECONDS           whole AI call, retries included (default: 60)
+//	LLM_MAX_RETRIES               retries on 429/529/5xx and network errors (default: 2)
+//	LLM_BREAKER_THRESHOLD         consecutive failures that open the circuit (default: 5)
+//	LLM_BREAKER_COOLDOWN_SECONDS  how long an open circuit fails fast (default: 30)
+//	LLM_BYOK_BASE_URL_HOSTS       hosts allowed as a user's own base_url, comma-separated (default: any public https host)
+//
+// It is called lazily by For, so calling it from main is optional.
+func Init() {
+	initOnce.Do(func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		RequestTimeout = time.Duration(envInt("LLM_TIMEOUT_SECONDS", int(RequestTimeout/time.Second))) * time.Second
+		DefaultClient.MaxRetries = envInt("LLM_MAX_RETRIES", DefaultClient.MaxRetries)
+		byokClient.MaxRetries = DefaultClient.MaxRetries
+		BreakerThreshold = max(envInt("LLM_BREAKER_THRESHOLD", BreakerThreshold), 1)
+		BreakerCooldown = time.Duration(envInt("LLM_BREAKER_COOLDOWN_SECONDS", int(BreakerCooldown/time.Second))) * time.Second
+		for _, host := range strings.Split(os.Getenv("LLM_BYOK_BASE_URL_HOSTS"), ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				BYOKBaseURLHosts = append(BYOKBaseURLHosts, host)
+			}
+		}
+
+		providers["anthropic"] = &Anthropic{
+			APIKey:   os.Getenv("ANTHROPIC_API_KEY"),
+			URL:      envString("ANTHROPIC_URL", AnthropicURL),
+			Settings: settingsFromEnv("ANTHROPIC", AnthropicModel, 2048),
+		}
+		providers["openai"] = &OpenAI{
+			APIKey:   os.Getenv("OPENAI_API_KEY"),
+			BaseURL:  envString("OPENAI_BASE_URL", OpenAIBaseURL),
+			Settings: settingsFromEnv("OPENAI", "", 2048),
+		}
+		if _, ok := providers["fake"]; !ok {
+			providers["fake"] = &Fake{}
+		}
+	})
+}
+
+// Register installs or replaces a provider by name, e.g. a Fake in tests.
+func Register(name string, p Provider) {
+	Init()
+	mu.Lock()
+	defer mu.Unlock()
+	providers[name] = p
+}
+
+// For returns the provider configured for a feature and user tier, with the
+// feature's token limit and temperature applied.
+func For(feature, tier string) Provider {
+	Init()
+
+	name := envString("LLM_PROVIDER", "anthropic")
+	if v := os.Getenv("LLM_PROVIDER_TIER_" + envKey(tier)); tier != "" && v != "" {
+		name = v
+	}
+	if v := os.Getenv("LLM_PROVIDER_" + envKey(feature)); feature != "" && v != "" {
+		name = v
+	}
+
+	mu.RLock()
+	p, ok := providers[name]
+	mu.RUnlock()
+	if !ok {
+		return unknownProvider(name)
+	}
+
+	return withFeature(feature, p)
+}
+
+// ForKey returns a provider that calls the vendor with the user's own API key
+// (bring-your-own-key), keeping the server's model and limits for the feature.
+// baseURL is only used by OpenAI-compatible providers and defaults to the server's;
+// a custom one must pass CheckBaseURL and is called through byokClient.
+func ForKey(feature, name, apiKey, baseURL string) (Provider, error) {
+	Init()
+
+	mu.RLock()
+	configured := providers[name]
+	mu.RUnlock()
+
+	var p Provider
+	switch base := configured.(type) {
+	case *Anthropic:
+		p = &Anthropic{APIKey: apiKey, URL: base.URL, Settings: base.Settings}
+	case *OpenAI:
+		if baseURL == "" {
+			p = &OpenAI{APIKey: apiKey, BaseURL: base.BaseURL, Settings: base.Settings}
+			break
+		}
+		if err := CheckBaseURL(baseURL); err != nil {
+			return nil, err
+		}
+		p = &OpenAI{APIKey: apiKey, BaseURL: baseURL, Settings: base.Settings, Client: byokClient}
+	default:
+		return nil, fmt.Errorf("provider %q does not support custom API keys", name)
+	}
+	return withFeature(feature, p), nil
+}
+
+// withFeature applies LLM_<FEATURE>_MAX_TOKENS / _TEMPERATURE, if configured.
+func withFeature(feature string, p Provider) Provider {
+	overrides := settingsFromEnv("LLM_"+envKey(feature), "", 0)
+	if overrides.MaxTokens == 0 && overrides.Temperature == nil {
+		return p
+	}
+	return &withSettings{Provider: p, overrides: overrides}
+}
+
+// withSettings applies feature-level defaults that the request did not set.
+type withSettings struct {
+	Provider
+	overrides Settings
+}
+
+func (w *withSettings) fill(req Request) Request {
+	if req.MaxTokens == 0 {
+		req.MaxTokens = w.overrides.MaxTokens
+	}
+	if req.Temperature == nil {
+		req.Temperature = w.overrides.Temperature
+	}
+	return req
+}
+
+func (w *withSettings) Complete(ctx context.Context, req Request) (Response, error) {
+	return w.Provider.Complete(ctx, w.fill(req))
+}
+
+func (w *withSettings) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	return w.Provider.Stream(ctx, w.fill(req), onDelta)
+}
+
+// unknownProvider fails every call, so a typo in the configuration surfaces as an API error.
+type unknownProvider string
+
+func (u unknownProvider) Name() string { return string(u) }
+
+func (u unknownProvider) Complete(ctx context.Context, req Request) (Response, error) {
+	return Response{}, fmt.Errorf("LLM provider %q non configurato sul server", string(u))
+}
+
+func (u unknownProvider) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	return u.Complete(ctx, req)
+}
+
+func settingsFromEnv(prefix, defaultModel string, defaultMaxTokens int) Settings {
+	s := Settings{
+		Model:     envString(prefix+"_MODEL", defaultModel),
+		MaxTokens: defaultMaxTokens,
+	}
+	if n, err := strconv.Atoi(os.Getenv(prefix + "_MAX_TOKENS")); err == nil && n > 0 {
+		s.MaxTokens = n
+	}
+	if f, err := strconv.ParseFloat(os.Getenv(prefix+"_TEMPERATURE"), 64); err == nil {
+		s.Temperature = &f
+	}
+	return s
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+func envKey(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(s, "-", "_"))
+}
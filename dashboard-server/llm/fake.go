@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeResponse is valid JSON for every AI feature, so the fake provider can
+// serve any endpoint without knowing which one is calling.
+const FakeResponse = `{"title": "Titolo di prova", "purposes": ["Scopo di prova 1", "Scopo di prova 2", "Scopo di prova 3"], "comment": "Commento di prova.", "translation": "Commento di prova."}`
+
+// Fake is a deterministic provider for tests and local development: it
+// returns Responses in order (cycling), or FakeResponse when none are set,
+// and records every request it received.
+type Fake struct {
+	Responses []string
+
+	mu       sync.Mutex
+	calls    int
+	Requests []Request
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) Complete(ctx context.Context, req Request) (Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Requests = append(f.Requests, req)
+	text := FakeResponse
+	if len(f.Responses) > 0 {
+		text = f.Responses[f.calls%len(f.Responses)]
+	}
+	f.calls++
+
+	return Response{
+		Text:         text,
+		Model:        "fake",
+		InputTokens:  countWords(req),
+		OutputTokens: len(strings.Fields(text)),
+	}, nil
+}
+
+// Stream sends the response word by word.
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	resp, err := f.Complete(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	for _, word := range strings.SplitAfter(resp.Text, " ") {
+		if err := onDelta(word); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+func countWords(req Request) int {
+	n := len(strings.Fields(req.System))
+	for _, m := range req.Messages {
+		n += len(strings.Fields(m.Content))
+	}
+	return n
+}
@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI speaks the /chat/completions protocol, which most self-hosted
+// servers (vLLM, Ollama, llama.cpp, LiteLLM) also expose.
+type OpenAI struct {
+	APIKey   string // optional for self-hosted servers
+	BaseURL  string
+	Settings Settings
+	Client   *Client // nil: DefaultClient
+}
+
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string `json:"type"` // "json_schema"
+	JSONSchema struct {
+		Name   string         `json:"name"`
+		Schema map[string]any `json:"schema"`
+		Strict bool           `json:"strict"`
+	} `json:"json_schema"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+		Delta   Message `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+func (o *OpenAI) Name() string { return "openai" }
+
+func (o *OpenAI) client() *Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return DefaultClient
+}
+
+func (o *OpenAI) newRequest(ctx context.Context, req Request, stream bool) (*http.Request, error) {
+	model, maxTokens, temperature := o.Settings.apply(req)
+	if model == "" {
+		return nil, fmt.Errorf("OPENAI_MODEL non configurato sul server")
+	}
+
+	messages := req.Messages
+	if req.System != "" {
+		messages = append([]Message{{Role: "system", Content: req.System}}, messages...)
+	}
+	payload := openAIRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		Stream:      stream,
+	}
+	if req.Schema != nil && !stream {
+		payload.ResponseFormat = &openAIResponseFormat{Type: "json_schema"}
+		payload.ResponseFormat.JSONSchema.Name = req.Schema.Name
+		payload.ResponseFormat.JSONSchema.Schema = req.Schema.Parameters
+		payload.ResponseFormat.JSONSchema.Strict = true
+	}
+	body, _ := json.Marshal(payload)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(o.BaseURL, "/")+"/chat/completions", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	return httpReq, nil
+}
+
+func (o *OpenAI) Complete(ctx context.Context, req Request) (Response, error) {
+	resp, err := o.client().Do(ctx, func() (*http.Request, error) { return o.newRequest(ctx, req, false) })
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(o.Name(), resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return Response{}, err
+	}
+	if len(openAIResp.Choices) == 0 || openAIResp.Choices[0].Message.Content == "" {
+		return Response{}, fmt.Errorf("Risposta OpenAI vuota o non valida")
+	}
+
+	out := Response{Text: openAIResp.Choices[0].Message.Content, Model: openAIResp.Model}
+	if openAIResp.Usage != nil {
+		out.InputTokens = openAIResp.Usage.PromptTokens
+		out.OutputTokens = openAIResp.Usage.CompletionTokens
+	}
+	return out, nil
+}
+
+func (o *OpenAI) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	resp, err := o.client().Do(ctx, func() (*http.Request, error) { return o.newRequest(ctx, req, true) })
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(o.Name(), resp)
+	}
+
+	var out Response
+	var full strings.Builder
+	err = scanSSE(resp.Body, func(data string) (bool, error) {
+		if data == "[DONE]" {
+			return true, nil
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, nil
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.InputTokens = chunk.Usage.PromptTokens
+			out.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			full.WriteString(chunk.Choices[0].Delta.Content)
+			return false, onDelta(chunk.Choices[0].Delta.Content)
+		}
+		return false, nil
+	})
+	out.Text = full.String()
+	return out, err
+}
@@ -0,0 +1,106 @@
+// Package llm abstracts the language model behind the AI endpoints.
+//
+// Handlers never talk to a vendor API directly: they ask For(feature, tier)
+// for a Provider and send it a Request. Which provider serves which feature
+// or tier, and with which model, token limit and temperature, is configured
+// through the environment (see Init).
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Features, used to pick a provider and its limits.
+const (
+	FeaturePurposes = "purposes"
+	FeatureComment  = "comment"
+	FeatureReply    = "reply"
+	FeaturePost     = "post"
+	FeatureEnrich   = "enrichment" // background post classification
+)
+
+type Message struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+type Request struct {
+	System   string
+	Messages []Message
+
+	// Optional per-request overrides of the provider configuration.
+	Model       string
+	MaxTokens   int
+	Temperature *float64
+
+	// Schema, when set, asks the provider to answer with a JSON object matching
+	// it (tool use on Anthropic, response_format on OpenAI-compatible servers).
+	// Response.Text is then the JSON object. Stream ignores it: partial
+	// structured output is not useful to show as it arrives.
+	Schema *Schema
+}
+
+// Schema describes the JSON object expected back from the model.
+type Schema struct {
+	Name        string // identifier, [a-zA-Z0-9_-]
+	Description string
+	Parameters  map[string]any // JSON Schema of the object
+}
+
+// UserRequest is the common single-turn request.
+func UserRequest(system, user string) Request {
+	return Request{System: system, Messages: []Message{{Role: "user", Content: user}}}
+}
+
+type Response struct {
+	Text         string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// DecodeObject decodes the JSON object in a model answer into v, ignoring any
+// text around it (code fences, a sentence before or after).
+func DecodeObject(text string, v any) error {
+	start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}')
+	if start == -1 || end <= start {
+		return errors.New("no JSON object in output")
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req Request) (Response, error)
+	// Stream calls onDelta for every text chunk and returns the full response.
+	// Returning an error from onDelta aborts the stream.
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error)
+}
+
+// Settings are the per-provider defaults, overridable per request.
+type Settings struct {
+	Model       string
+	MaxTokens   int
+	Temperature *float64
+}
+
+func (s Settings) apply(req Request) (model string, maxTokens int, temperature *float64) {
+	model, maxTokens, temperature = s.Model, s.MaxTokens, s.Temperature
+	if req.Model != "" {
+		model = req.Model
+	}
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		temperature = req.Temperature
+	}
+	return
+}
@@ -5,9 +5,13 @@ import (
 	"os"
 
 	"dashboard-server/database"
+	"dashboard-server/enrichment"
 	"dashboard-server/handlers"
+	"dashboard-server/llm"
 	"dashboard-server/logger"
 	"dashboard-server/middleware"
+	"dashboard-server/prompts"
+	"dashboard-server/scoring"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -25,13 +29,19 @@ func main() {
 
 	database.ConnectRedis()
 	database.ConnectNeo4j()
+	database.RunMigrations()
+	llm.Init() // LLM providers per feature/tier (LLM_PROVIDER, ANTHROPIC_*, OPENAI_*)
+	if err := prompts.Load(); err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	scoring.Load() // Warm Reach weights (REACH_WEIGHT_*, REACH_HALF_LIFE_DAYS)
 
 	app := fiber.New()
 	app.Use(middleware.RequestLogger) // structured request logging
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:3000,http://127.0.0.1:3000,http://localhost:3001,http://127.0.0.1:3001,http://localhost:5001,http://127.0.0.1:5001",
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET,POST,OPTIONS",
+		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
 		AllowCredentials: true,
 	}))
 
@@ -40,15 +50,47 @@ func main() {
 	api.Get("/stats", handlers.GetStats)
 	api.Get("/activity", handlers.GetActivity)
 	api.Get("/bridge-targets", handlers.GetBridgeTargets)
+	api.Post("/bridge-targets/:slug/dismiss", handlers.DismissBridgeTarget)
+	api.Delete("/bridge-targets/:slug/dismiss", handlers.RestoreBridgeTarget)
 	api.Get("/connections/stats", handlers.GetConnectionsStats)
 	api.Get("/connections/list", handlers.GetConnectionsList)
 	api.Get("/connections/insights", handlers.GetNetworkInsights)
 	api.Get("/connections/overlap", handlers.GetNetworkOverlap)
+	api.Get("/jobs", handlers.GetJobs)
+	api.Get("/companies", handlers.GetCompanies)
+	api.Get("/companies/:slug", handlers.GetCompany)
 
 	// AI routes (Managed Claude)
 	api.Post("/ai/suggest-purposes", handlers.SuggestPurposes)
 	api.Post("/ai/generate-comment", handlers.GenerateComment)
+	api.Post("/ai/generate-comment/stream", handlers.GenerateCommentStream)
+	api.Post("/ai/draft-replies", handlers.DraftReplies)
+	api.Post("/ai/draft-post", handlers.DraftPost)
+	api.Post("/ai/generations/:id/outcome", handlers.ReportOutcome)
+	api.Get("/ai/analytics", handlers.GetAIAnalytics)
 	api.Get("/user/usage", handlers.GetUsage)
+	api.Get("/user/preferences", handlers.GetPreferences)
+	api.Put("/user/preferences", handlers.UpdatePreferences)
+	api.Get("/user/style-profile", handlers.GetStyleProfile)
+	api.Put("/user/style-profile", handlers.UpdateStyleProfile)
+	api.Delete("/user/style-profile", handlers.DeleteStyleProfile)
+	api.Get("/user/role-categories", handlers.GetRoleCategories)
+	api.Put("/user/role-categories", handlers.UpdateRoleCategories)
+
+	// Bring-your-own API key (stored encrypted, bypasses the free-tier cap)
+	api.Get("/user/api-key", handlers.GetAPIKey)
+	api.Put("/user/api-key", handlers.SetAPIKey)
+	api.Post("/user/api-key/test", handlers.TestAPIKey)
+	api.Delete("/user/api-key", handlers.DeleteAPIKey)
+
+	// Operator endpoints (X-Admin-Token)
+	admin := app.Group("/admin", middleware.AdminProtected())
+	admin.Get("/ai/analytics", handlers.GetAIAnalyticsAll)
+
+	// Background classification of captured posts (ENRICH_ENABLED)
+	go enrichment.Start()
+	// Custom role categories of new connections and re-parsed headlines
+	go handlers.StartRoleClassification()
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminProtected guards operator-only endpoints with the static ADMIN_TOKEN,
+// sent as X-Admin-Token. Without ADMIN_TOKEN the endpoints are disabled.
+func AdminProtected() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expected := os.Getenv("ADMIN_TOKEN")
+		if expected == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admin endpoints are disabled"})
+		}
+
+		token := c.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid admin token"})
+		}
+
+		return c.Next()
+	}
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"context"
+	"log/slog"
+	"strings"
 	"time"
 
 	"dashboard-server/logger"
@@ -8,15 +11,23 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// Bodies of these paths carry secrets (the user's own LLM API key) and are
+// never logged, not even at debug level.
+var sensitivePaths = []string{"/api/user/api-key"}
+
+const maxLoggedBody = 4096
+
 // RequestLogger logs every incoming request with method, path, status code,
-// latency, and user_id. It also logs request/response bodies for debugging.
+// latency, and user_id. Request/response bodies, which hold user content,
+// are logged only at debug level (LOG_LEVEL=debug), for debugging.
 func RequestLogger(c *fiber.Ctx) error {
 	start := time.Now()
+	debug := logger.L.Enabled(context.Background(), slog.LevelDebug) && !isSensitivePath(c.Path())
 
-	// Capture request body (limit size for safety)
-	reqBody := string(c.Request().Body())
-	if len(reqBody) > 4096 {
-		reqBody = reqBody[:4096] + "... (truncated)"
+	// Capture the request body before the handler runs
+	var reqBody string
+	if debug {
+		reqBody = truncateBody(string(c.Request().Body()))
 	}
 
 	// Process request
@@ -26,12 +37,6 @@ func RequestLogger(c *fiber.Ctx) error {
 	status := c.Response().StatusCode()
 	uid := c.Locals("user_id")
 
-	// Capture response body (limit size for safety)
-	respBody := string(c.Response().Body())
-	if len(respBody) > 4096 {
-		respBody = respBody[:4096] + "... (truncated)"
-	}
-
 	attrs := []any{
 		"method", c.Method(),
 		"path", c.Path(),
@@ -43,22 +48,45 @@ func RequestLogger(c *fiber.Ctx) error {
 		attrs = append(attrs, "user_id", uid)
 	}
 
-	// Log with bodies (using debug level if needed, or structured attributes)
 	logMsg := "request processed"
 	if status >= 400 {
 		logMsg = "request failed"
 	}
 
-	fullAttrs := append(attrs, "req_body", reqBody, "resp_body", respBody)
-
 	switch {
 	case status >= 500:
-		logger.Error(logMsg, fullAttrs...)
+		logger.Error(logMsg, attrs...)
 	case status >= 400:
-		logger.Warn(logMsg, fullAttrs...)
+		logger.Warn(logMsg, attrs...)
 	default:
-		logger.Info(logMsg, fullAttrs...)
+		logger.Info(logMsg, attrs...)
+	}
+
+	if debug {
+		// Streamed bodies (SSE) are skipped: reading them here would buffer
+		// the whole stream.
+		respBody := "(stream)"
+		if !c.Response().IsBodyStream() {
+			respBody = truncateBody(string(c.Response().Body()))
+		}
+		logger.Debug("request bodies", append(attrs, "req_body", reqBody, "resp_body", respBody)...)
 	}
 
 	return err
 }
+
+func isSensitivePath(path string) bool {
+	for _, p := range sensitivePaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateBody(body string) string {
+	if len(body) > maxLoggedBody {
+		return body[:maxLoggedBody] + "... (truncated)"
+	}
+	return body
+}
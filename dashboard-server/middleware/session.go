@@ -12,8 +12,13 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// DeletedUsersKey is the Redis set of deleted accounts, filled by
+// auth-service when an account deletion starts.
+const DeletedUsersKey = "deleted_users"
+
 // AuthRequired reads the "session" cookie OR the "Authorization" header.
-// It validates against Redis (cookies) or public.pem (JWT).
+// It validates against Redis (cookies) or public.pem (JWT). Deleted accounts
+// are rejected, even with a JWT issued before the deletion.
 func AuthRequired(c *fiber.Ctx) error {
 	// 1. Try Session Cookie (for Dashboard UI)
 	token := c.Cookies("session")
@@ -24,7 +29,7 @@ func AuthRequired(c *fiber.Ctx) error {
 			if _, err := fmt.Sscan(val, &userID); err == nil {
 				c.Locals("user_id", userID)
 				c.Locals("tier", "free") // Dashboard session defaults to free for now
-				return c.Next()
+				return next(c)
 			}
 		}
 	}
@@ -64,7 +69,7 @@ func AuthRequired(c *fiber.Ctx) error {
 						} else {
 							c.Locals("tier", "free")
 						}
-						return c.Next()
+						return next(c)
 					}
 				}
 			}
@@ -74,3 +79,25 @@ func AuthRequired(c *fiber.Ctx) error {
 	logger.Warn("auth rejected: invalid or missing credentials", "ip", c.IP(), "path", c.Path())
 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 }
+
+// next passes an authenticated request on, unless its account was deleted.
+// If Redis cannot tell, the request goes through: dashboard writes only
+// MATCH the User node, so they cannot bring a purged account back.
+func next(c *fiber.Ctx) error {
+	deleted, err := database.RedisClient.SIsMember(database.Ctx, DeletedUsersKey, UserID(c)).Result()
+	if err != nil {
+		logger.Warn("deleted users check failed", "err", err, "user_id", UserID(c))
+	}
+	if deleted {
+		logger.Warn("auth rejected: deleted account", "user_id", UserID(c), "path", c.Path())
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	return c.Next()
+}
+
+// UserID returns the authenticated user's ID, typed as it is stored on
+// (:User {id}) in Neo4j (an integer, same as event-service).
+func UserID(c *fiber.Ctx) int64 {
+	v, _ := c.Locals("user_id").(uint)
+	return int64(v)
+}
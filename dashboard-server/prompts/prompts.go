@@ -0,0 +1,499 @@
+// Package prompts renders the AI prompts from versioned text/template files.
+//
+// Each prompt has one file per version, templates/<name>/<version>.tmpl,
+// defining a "system" and a "user" template. Versions are never edited once
+// they served traffic: a change is a new file, so every Generation can be
+// traced back to the exact prompt that produced it (its version and the
+// user's language, see Rendered.Language).
+//
+// The originals are in Italian. A version can have translations,
+// <version>.<lang>.tmpl: users get the one in their language if any, else
+// the English one, else the original.
+//
+// Which version a user gets is decided by Pick:
+//
+//	PROMPT_EXPERIMENT_<NAME>  A/B weights, e.g. PROMPT_EXPERIMENT_COMMENT=v1:50,v2:50
+//	PROMPT_VERSION_<NAME>     fixed version when no experiment runs (default: latest)
+//	PROMPTS_DIR               read templates from this directory instead of the
+//	                          embedded ones (same layout), e.g. to try a prompt without a rebuild
+//
+// Assignment is a hash of the prompt name and user ID, so a user keeps the
+// same variant for the whole experiment. A request that needs a feature the
+// assigned version lacks (see requiredFields) gets the latest version
+// instead.
+package prompts
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"dashboard-server/i18n"
+)
+
+// Prompt names.
+const (
+	Purposes  = "purposes"
+	Comment   = "comment"
+	Reply     = "reply"
+	Post      = "post"
+	Enrich    = "enrichment"
+	Jobs      = "jobs"
+	Headlines = "headlines"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Trigger is the comment the user chose to reply to.
+type Trigger struct {
+	AuthorName  string
+	CommentText string
+}
+
+// StyleProfile is how the user writes (templates since comment/v3).
+type StyleProfile struct {
+	Role          string
+	Expertise     []string
+	Tone          string
+	BannedPhrases []string
+	Samples       []string
+}
+
+// GraphContext is what the user's graph knows about the post (templates
+// since purposes/v3 and comment/v4).
+type GraphContext struct {
+	Author  *AuthorContext
+	Bridges []BridgeContext
+	Topics  []string // topics of the post the user already engaged with
+}
+
+type AuthorContext struct {
+	Name            string
+	Connected       bool   // 1st degree connection of the user
+	Degree          string // as last seen by the user, e.g. "2nd"
+	Interactions    int64  // user's past actions on the author's posts
+	LastInteraction string // date, "" if never
+}
+
+// BridgeContext is a co-commenter of the post who also comments on posts of
+// people the user engages with.
+type BridgeContext struct {
+	Name           string
+	Connected      bool
+	AlsoCommentsOn []string // authors
+}
+
+// Data is everything a template can use. Fields a prompt does not need are
+// simply left empty.
+type Data struct {
+	PostText            string
+	CommentsText        string
+	ProfessionalContext string
+	UserName            string
+	Purposes            []string
+	Trigger             *Trigger
+
+	// Language of the user (purposes, translation), e.g. "en", and how the
+	// prompt names it ("inglese", "English"), set by RenderVersion.
+	// Templates since v2.
+	Language     string
+	LanguageName string
+
+	Profile *StyleProfile // nil when the user has none
+	Graph   *GraphContext // nil when the graph knows nothing about the post
+
+	// Number of alternative comments to write; 0 or 1 is a single comment
+	// (templates since comment/v5).
+	Variants   int
+	Regenerate *Regeneration // nil unless the user asked to rewrite a comment
+
+	Replies []ReplyTarget // comments to answer, by priority (reply templates)
+
+	Ideation *Ideation // post templates
+
+	Posts []EnrichPost // enrichment and jobs templates
+
+	Headlines []Headline // headlines templates
+}
+
+// ReplyTarget is a comment received on the user's own post, to answer
+// (reply templates). Index is how the model refers to it in its answer.
+type ReplyTarget struct {
+	Index      int
+	AuthorName string
+	Headline   string
+	Text       string
+	Connected  bool // 1st degree connection of the user
+	Bridge     bool // also comments on posts the user engages with
+	VIP        bool // executive, lead or investor, from the parsed headline
+}
+
+// Ideation is what the user's topic graph suggests for a post of their own
+// (post templates).
+type Ideation struct {
+	Topics   []TopicStat    // topics the user engages with most
+	Trending []TrendingPost // engaging recent posts in the user's network
+	Topic    string         // topic chosen by the user, "" to let the model pick
+	Angle    string         // angle chosen by the user, "" to let the model pick
+	Notes    string         // the user's own idea, experience or data to build on
+	Hooks    int            // hook variants to write
+}
+
+type TopicStat struct {
+	Name  string
+	Posts int64 // posts on the topic the user acted on
+}
+
+type TrendingPost struct {
+	AuthorName string
+	Text       string
+	Engagement int64 // comments and reshares seen
+	Topics     []string
+}
+
+// EnrichPost is a post to classify (enrichment templates) or to extract job
+// openings from (jobs templates, which also use the author and the companies
+// the post mentions).
+type EnrichPost struct {
+	Index          int
+	Text           string
+	AuthorName     string
+	AuthorHeadline string
+	Companies      []string
+}
+
+// Headline is a LinkedIn headline the rule-based parser could not read.
+type Headline struct {
+	Index int
+	Text  string
+}
+
+// Regeneration is a previous comment and how the user wants it rewritten.
+type Regeneration struct {
+	PreviousText string
+	Instruction  string
+}
+
+// Rendered is a prompt ready to be sent, with the version that produced it.
+type Rendered struct {
+	System   string
+	User     string
+	Version  string // e.g. "v2"
+	Language string // language the prompt is written in, e.g. "it"
+}
+
+// original is the language of the <version>.tmpl files.
+const original = "it"
+
+// ErrUnsupported is returned by RenderVersion when the data asks for a
+// feature the version's template does not use.
+var ErrUnsupported = errors.New("prompts: version does not support the request")
+
+// requiredFields are the Data fields that change what the answer must be: a
+// template that never reads them would silently produce the wrong thing (one
+// comment instead of variants, a new comment instead of a rewrite). The
+// context fields (Profile, Graph, ...) are not here: leaving them out is a
+// legitimate experiment.
+var requiredFields = []struct {
+	name string
+	set  func(Data) bool
+}{
+	{"Variants", func(d Data) bool { return d.Variants > 1 }},
+	{"Regenerate", func(d Data) bool { return d.Regenerate != nil }},
+}
+
+type prompt struct {
+	*template.Template
+	fields map[string]bool // Data fields the template reads
+}
+
+var (
+	loadOnce  sync.Once
+	loadErr   error
+	templates map[string]map[string]map[string]prompt // name -> version -> language -> template
+)
+
+var funcs = template.FuncMap{
+	"json": func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+	"join": strings.Join,
+}
+
+// Load parses every template. It runs once and is called lazily by Render;
+// main calls it to fail at startup on a broken template.
+func Load() error {
+	loadOnce.Do(func() {
+		var fsys fs.FS = embedded
+		root := "templates"
+		if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+			fsys, root = os.DirFS(dir), "."
+		}
+		templates, loadErr = parseAll(fsys, root)
+	})
+	return loadErr
+}
+
+func parseAll(fsys fs.FS, root string) (map[string]map[string]map[string]prompt, error) {
+	files, err := fs.Glob(fsys, path.Join(root, "*", "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("prompts: no templates found")
+	}
+
+	all := map[string]map[string]map[string]prompt{}
+	for _, file := range files {
+		name := path.Base(path.Dir(file))
+		version, lang, _ := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if lang == "" {
+			lang = original
+		}
+
+		t, err := template.New(name+"/"+version).Funcs(funcs).ParseFS(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("prompts: %s: %w", file, err)
+		}
+		for _, part := range []string{"system", "user"} {
+			if t.Lookup(part) == nil {
+				return nil, fmt.Errorf("prompts: %s does not define %q", file, part)
+			}
+		}
+		if all[name] == nil {
+			all[name] = map[string]map[string]prompt{}
+		}
+		if all[name][version] == nil {
+			all[name][version] = map[string]prompt{}
+		}
+		fields := map[string]bool{}
+		for _, part := range t.Templates() {
+			if part.Tree != nil {
+				collectFields(part.Tree.Root, fields)
+			}
+		}
+		all[name][version][lang] = prompt{Template: t, fields: fields}
+	}
+	for name, versions := range all {
+		for version, langs := range versions {
+			if _, ok := langs[original]; !ok {
+				return nil, fmt.Errorf("prompts: %s/%s has translations but no %s.tmpl", name, version, version)
+			}
+		}
+	}
+	return all, nil
+}
+
+// Render builds the prompt for a user, picking the version with Pick, or
+// the latest one if the picked version does not support the request.
+func Render(name string, userID int64, data Data) (Rendered, error) {
+	if err := Load(); err != nil {
+		return Rendered{}, err
+	}
+	version, err := Pick(name, userID)
+	if err != nil {
+		return Rendered{}, err
+	}
+	rendered, err := RenderVersion(name, version, data)
+	if versions := Versions(name); errors.Is(err, ErrUnsupported) && version != versions[len(versions)-1] {
+		return RenderVersion(name, versions[len(versions)-1], data)
+	}
+	return rendered, err
+}
+
+// RenderVersion builds a specific version of a prompt, in the translation
+// for data.Language. It fails with ErrUnsupported if data sets one of
+// requiredFields the template never reads.
+func RenderVersion(name, version string, data Data) (Rendered, error) {
+	if err := Load(); err != nil {
+		return Rendered{}, err
+	}
+	translations, ok := templates[name][version]
+	if !ok {
+		return Rendered{}, fmt.Errorf("prompts: unknown prompt %s/%s", name, version)
+	}
+	lang := translation(translations, data.Language)
+	t := translations[lang]
+	if data.Language != "" {
+		data.LanguageName = i18n.Get(data.Language).PromptName(lang)
+	}
+	for _, f := range requiredFields {
+		if f.set(data) && !t.fields[f.name] {
+			return Rendered{}, fmt.Errorf("%w: %s/%s does not use %s", ErrUnsupported, name, version, f.name)
+		}
+	}
+
+	var system, user strings.Builder
+	if err := t.ExecuteTemplate(&system, "system", data); err != nil {
+		return Rendered{}, err
+	}
+	if err := t.ExecuteTemplate(&user, "user", data); err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{System: system.String(), User: user.String(), Version: version, Language: lang}, nil
+}
+
+// translation returns the language of the translation to use for a user
+// language: the same, else English, else the original.
+func translation(translations map[string]prompt, userLang string) string {
+	if _, ok := translations[userLang]; ok {
+		return userLang
+	}
+	if _, ok := translations["en"]; ok && userLang != "" {
+		return "en"
+	}
+	return original
+}
+
+// collectFields adds to fields the first name of every field reference under
+// node: ".Regenerate.Instruction" and "$.Variants" count as Regenerate and
+// Variants. Fields read inside a with or range count too, which can only
+// make the set larger than the Data fields actually read.
+func collectFields(node parse.Node, fields map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, fields)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, fields)
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, fields)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, fields)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, fields)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, fields)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, fields)
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, fields)
+	case *parse.FieldNode:
+		fields[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			fields[n.Ident[1]] = true
+		}
+	}
+}
+
+func collectBranch(n *parse.BranchNode, fields map[string]bool) {
+	collectFields(n.Pipe, fields)
+	collectFields(n.List, fields)
+	collectFields(n.ElseList, fields)
+}
+
+// Pick returns the version of a prompt assigned to a user.
+func Pick(name string, userID int64) (string, error) {
+	if err := Load(); err != nil {
+		return "", err
+	}
+	versions := Versions(name)
+	if len(versions) == 0 {
+		return "", fmt.Errorf("prompts: unknown prompt %s", name)
+	}
+
+	key := strings.ToUpper(name)
+	var active variants
+	for _, v := range parseVariants(os.Getenv("PROMPT_EXPERIMENT_" + key)) {
+		if _, ok := templates[name][v.version]; ok {
+			active = append(active, v)
+		}
+	}
+	if len(active) > 0 {
+		h := fnv.New32a()
+		fmt.Fprintf(h, "%s:%d", name, userID)
+		return active.pick(h.Sum32()), nil
+	}
+	if v := os.Getenv("PROMPT_VERSION_" + key); v != "" {
+		return v, nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+// Versions lists the available versions of a prompt, oldest first.
+func Versions(name string) []string {
+	if Load() != nil {
+		return nil
+	}
+	var versions []string
+	for v := range templates[name] {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionNumber(versions[i]) < versionNumber(versions[j]) })
+	return versions
+}
+
+func versionNumber(v string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(v, "v"))
+	return n
+}
+
+type variant struct {
+	version string
+	weight  uint32
+}
+
+type variants []variant
+
+// parseVariants reads "v1:50,v2:50"; a variant without weight counts 1.
+func parseVariants(s string) variants {
+	var out variants
+	for _, part := range strings.Split(s, ",") {
+		version, weight, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if version == "" {
+			continue
+		}
+		w := uint64(1)
+		if weight != "" {
+			parsed, err := strconv.ParseUint(weight, 10, 32)
+			if err != nil || parsed == 0 {
+				continue
+			}
+			w = parsed
+		}
+		out = append(out, variant{version: version, weight: uint32(w)})
+	}
+	return out
+}
+
+func (vs variants) pick(hash uint32) string {
+	var total uint32
+	for _, v := range vs {
+		total += v.weight
+	}
+	n := hash % total
+	for _, v := range vs {
+		if n < v.weight {
+			return v.version
+		}
+		n -= v.weight
+	}
+	return vs[len(vs)-1].version
+}
@@ -0,0 +1,271 @@
+// Package scoring ranks the targets of the Warm Reach Map: how likely an
+// introduction to a target through a given bridge is to work.
+//
+// A candidate (target, bridge) pair is scored on five factors, each a value
+// between 0 and 1:
+//
+//	recency    how recently the path was seen, or the user engaged with the
+//	           bridge, halving every REACH_HALF_LIFE_DAYS
+//	bridge     the user's relationship with the bridge: connected, and how
+//	           often the user engages with their posts and threads
+//	seniority  the target's seniority, parsed from the headline
+//	topics     overlap between the topics the user engages with and the
+//	           target's posts and comments
+//	degree     the target's connection degree: a mutual connection (KNOWS)
+//	           or an observed 2nd degree beats a co-commenter
+//
+// The score is the weighted mean of the factors, 0-100. Weights come from
+// the environment, default in parentheses; 0 disables a factor:
+//
+//	REACH_WEIGHT_RECENCY    (3)
+//	REACH_WEIGHT_BRIDGE     (3)
+//	REACH_WEIGHT_SENIORITY  (1)
+//	REACH_WEIGHT_TOPICS     (2)
+//	REACH_WEIGHT_DEGREE     (2)
+//	REACH_HALF_LIFE_DAYS    (90)
+package scoring
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"dashboard-server/logger"
+)
+
+// Factor names, as returned in Explanation.
+const (
+	FactorRecency   = "recency"
+	FactorBridge    = "bridge"
+	FactorSeniority = "seniority"
+	FactorTopics    = "topics"
+	FactorDegree    = "degree"
+)
+
+// Path types, as in handlers.BridgeTarget.PathType.
+const (
+	PathKnows       = "knows"
+	PathCoCommenter = "co_commenter"
+)
+
+// Config holds the weights of the factors and the recency half-life.
+type Config struct {
+	Recency   float64
+	Bridge    float64
+	Seniority float64
+	Topics    float64
+	Degree    float64
+	HalfLife  time.Duration
+}
+
+// Default is the configuration without REACH_* variables.
+var Default = Config{Recency: 3, Bridge: 3, Seniority: 1, Topics: 2, Degree: 2, HalfLife: 90 * 24 * time.Hour}
+
+// Current is the configuration in use, set by Load.
+var Current = Default
+
+// Load reads the REACH_* variables over Default. Invalid or negative values
+// keep the default; if every weight is 0 the defaults are restored.
+func Load() {
+	cfg := Config{
+		Recency:   envWeight("REACH_WEIGHT_RECENCY", Default.Recency),
+		Bridge:    envWeight("REACH_WEIGHT_BRIDGE", Default.Bridge),
+		Seniority: envWeight("REACH_WEIGHT_SENIORITY", Default.Seniority),
+		Topics:    envWeight("REACH_WEIGHT_TOPICS", Default.Topics),
+		Degree:    envWeight("REACH_WEIGHT_DEGREE", Default.Degree),
+		HalfLife:  Default.HalfLife,
+	}
+	if days := envWeight("REACH_HALF_LIFE_DAYS", 0); days > 0 {
+		cfg.HalfLife = time.Duration(days * float64(24*time.Hour))
+	}
+	if cfg.total() == 0 {
+		logger.Warn("all warm reach weights are 0, using the defaults")
+		cfg = Default
+	}
+	Current = cfg
+}
+
+func envWeight(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return def
+	}
+	return v
+}
+
+func (c Config) total() float64 {
+	return c.Recency + c.Bridge + c.Seniority + c.Topics + c.Degree
+}
+
+// Candidate is what the graph tells about a (target, bridge) pair.
+type Candidate struct {
+	PathType string
+	Paths    int64     // mutual connections, or co-commenter paths through this bridge
+	LastSeen time.Time // latest KNOWS observation or co-comment on the path; zero if unknown
+
+	BridgeConnected bool
+	BridgeActions   int64     // user actions on the bridge's posts
+	BridgeThreads   int64     // posts the user acted on where the bridge commented
+	BridgeLast      time.Time // latest of those actions; zero if none
+
+	TargetSeniority string // Person.seniority
+	TargetDegree    string // degree observed by the user, "" if never observed
+
+	UserTopics   []string // topics of the posts the user acted on
+	TargetTopics []string // topics of the target's posts and comments
+}
+
+// Factor is one factor's share of a score. Points is its contribution to
+// the 0-100 total; Evidence holds the inputs, for the UI to explain it.
+type Factor struct {
+	Factor   string         `json:"factor"`
+	Value    float64        `json:"value"`
+	Weight   float64        `json:"weight"`
+	Points   float64        `json:"points"`
+	Evidence map[string]any `json:"evidence"`
+}
+
+// Score is a candidate's score with the breakdown of its factors, in the
+// order of the package documentation.
+type Score struct {
+	Total       float64  `json:"score"`
+	Explanation []Factor `json:"explanation"`
+}
+
+// Seniority values, from Person.seniority.
+var seniorityValues = map[string]float64{
+	"executive":   1,
+	"lead":        0.8,
+	"senior":      0.6,
+	"mid":         0.4,
+	"junior":      0.2,
+	"intern":      0.1,
+	"unspecified": 0.3,
+}
+
+// Engagements with a bridge for the relationship to count as 0.63 (1-1/e) of
+// the strongest.
+const bridgeEngagementScale = 5
+
+// Score scores a candidate at now.
+func (c Config) Score(cand Candidate, now time.Time) Score {
+	total := c.total()
+	if total == 0 {
+		c, total = Default, Default.total()
+	}
+
+	var factors []Factor
+	var sum float64
+	add := func(name string, weight, value float64, evidence map[string]any) {
+		value = math.Max(0, math.Min(1, value))
+		sum += weight * value
+		factors = append(factors, Factor{
+			Factor:   name,
+			Value:    round(value, 3),
+			Weight:   weight,
+			Points:   round(100*weight*value/total, 1),
+			Evidence: evidence,
+		})
+	}
+
+	// Recency: the freshest sign that the path is alive
+	last := cand.LastSeen
+	if cand.BridgeLast.After(last) {
+		last = cand.BridgeLast
+	}
+	recency := 0.0
+	evidence := map[string]any{"last_seen": nil, "days_ago": nil}
+	if !last.IsZero() {
+		age := max(now.Sub(last), 0)
+		recency = math.Pow(0.5, float64(age)/float64(c.HalfLife))
+		evidence = map[string]any{"last_seen": last.UTC().Format(time.RFC3339), "days_ago": int(age.Hours() / 24)}
+	}
+	add(FactorRecency, c.Recency, recency, evidence)
+
+	// Bridge: a connection is a good start, engagement makes it warm
+	engagement := 1 - math.Exp(-float64(cand.BridgeActions+cand.BridgeThreads)/bridgeEngagementScale)
+	bridge := engagement
+	if cand.BridgeConnected {
+		bridge = 0.4 + 0.6*engagement
+	}
+	add(FactorBridge, c.Bridge, bridge, map[string]any{
+		"connected": cand.BridgeConnected,
+		"actions":   cand.BridgeActions,
+		"threads":   cand.BridgeThreads,
+	})
+
+	seniority := cand.TargetSeniority
+	if _, ok := seniorityValues[seniority]; !ok {
+		seniority = "unspecified"
+	}
+	add(FactorSeniority, c.Seniority, seniorityValues[seniority], map[string]any{"seniority": seniority})
+
+	// Topics: shared over the smaller of the two sets, so a target with few
+	// topics all in the user's interests counts fully
+	shared := sharedTopics(cand.UserTopics, cand.TargetTopics)
+	topics := 0.0
+	if n := min(len(unique(cand.UserTopics)), len(unique(cand.TargetTopics))); n > 0 {
+		topics = float64(len(shared)) / float64(n)
+	}
+	add(FactorTopics, c.Topics, topics, map[string]any{"shared": shared})
+
+	add(FactorDegree, c.Degree, degreeValue(cand), map[string]any{
+		"path_type": cand.PathType,
+		"degree":    cand.TargetDegree,
+		"paths":     cand.Paths,
+	})
+
+	return Score{Total: round(100*sum/total, 1), Explanation: factors}
+}
+
+// degreeValue rates how close the target is: a mutual connection is a real
+// 2nd degree, more of them make it closer; a co-commenter counts for what
+// LinkedIn showed the user, if anything.
+func degreeValue(cand Candidate) float64 {
+	if cand.PathType == PathKnows {
+		return math.Min(1, 0.7+0.1*float64(cand.Paths))
+	}
+	switch cand.TargetDegree {
+	case "2nd":
+		return 0.6
+	case "3rd":
+		return 0.3
+	default:
+		return 0.15
+	}
+}
+
+func sharedTopics(user, target []string) []string {
+	in := map[string]bool{}
+	for _, t := range unique(user) {
+		in[t] = true
+	}
+	shared := []string{}
+	for _, t := range unique(target) {
+		if in[t] {
+			shared = append(shared, t)
+		}
+	}
+	return shared
+}
+
+// unique lowercases the topics and drops duplicates.
+func unique(list []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range list {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
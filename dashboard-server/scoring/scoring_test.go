@@ -0,0 +1,132 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestScoreFactorOrder(t *testing.T) {
+	s := Default.Score(Candidate{PathType: PathKnows, Paths: 1, LastSeen: now}, now)
+
+	want := []string{FactorRecency, FactorBridge, FactorSeniority, FactorTopics, FactorDegree}
+	if len(s.Explanation) != len(want) {
+		t.Fatalf("got %d factors, want %d", len(s.Explanation), len(want))
+	}
+	var points float64
+	for i, f := range s.Explanation {
+		if f.Factor != want[i] {
+			t.Errorf("factor %d = %q, want %q", i, f.Factor, want[i])
+		}
+		points += f.Points
+	}
+	if math.Abs(points-s.Total) > 0.3 {
+		t.Errorf("factor points add up to %v, total is %v", points, s.Total)
+	}
+}
+
+func TestScoreFactors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cand   Candidate
+		factor string
+		want   float64
+	}{
+		{"recency now", Candidate{LastSeen: now}, FactorRecency, 1},
+		{"recency one half-life ago", Candidate{LastSeen: now.Add(-Default.HalfLife)}, FactorRecency, 0.5},
+		{"recency uses the latest bridge action", Candidate{LastSeen: now.Add(-2 * Default.HalfLife), BridgeLast: now}, FactorRecency, 1},
+		{"recency in the future", Candidate{LastSeen: now.Add(time.Hour)}, FactorRecency, 1},
+		{"recency unknown", Candidate{}, FactorRecency, 0},
+		{"bridge unknown", Candidate{}, FactorBridge, 0},
+		{"bridge connected", Candidate{BridgeConnected: true}, FactorBridge, 0.4},
+		{"bridge engaged", Candidate{BridgeActions: 3, BridgeThreads: 2}, FactorBridge, 0.632},
+		{"seniority executive", Candidate{TargetSeniority: "executive"}, FactorSeniority, 1},
+		{"seniority missing", Candidate{}, FactorSeniority, 0.3},
+		{"seniority unknown value", Candidate{TargetSeniority: "wizard"}, FactorSeniority, 0.3},
+		{"topics none", Candidate{UserTopics: []string{"go"}}, FactorTopics, 0},
+		{"topics over the smaller set", Candidate{UserTopics: []string{"Go", "AI", "sales"}, TargetTopics: []string{"go", " go", "ai"}}, FactorTopics, 1},
+		{"topics partly shared", Candidate{UserTopics: []string{"go", "ai"}, TargetTopics: []string{"go", "design"}}, FactorTopics, 0.5},
+		{"degree knows", Candidate{PathType: PathKnows, Paths: 1}, FactorDegree, 0.8},
+		{"degree knows many", Candidate{PathType: PathKnows, Paths: 10}, FactorDegree, 1},
+		{"degree co-commenter 2nd", Candidate{PathType: PathCoCommenter, TargetDegree: "2nd"}, FactorDegree, 0.6},
+		{"degree co-commenter unknown", Candidate{PathType: PathCoCommenter}, FactorDegree, 0.15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, f := range Default.Score(tt.cand, now).Explanation {
+				if f.Factor == tt.factor && f.Value != tt.want {
+					t.Errorf("%s = %v, want %v", tt.factor, f.Value, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreZeroCandidate(t *testing.T) {
+	// Only the defaults of seniority (unspecified) and degree (co-commenter) count
+	s := Default.Score(Candidate{}, now)
+	want := round(100*(Default.Seniority*0.3+Default.Degree*0.15)/Default.total(), 1)
+	if s.Total != want {
+		t.Errorf("Total = %v, want %v", s.Total, want)
+	}
+}
+
+func TestScoreDisabledFactor(t *testing.T) {
+	cfg := Config{Bridge: 1, HalfLife: Default.HalfLife}
+	s := cfg.Score(Candidate{BridgeConnected: true, TargetSeniority: "executive", LastSeen: now}, now)
+	if s.Total != 40 {
+		t.Errorf("Total = %v, want 40", s.Total)
+	}
+	for _, f := range s.Explanation {
+		if f.Factor != FactorBridge && f.Points != 0 {
+			t.Errorf("disabled factor %s has %v points", f.Factor, f.Points)
+		}
+	}
+}
+
+func TestScoreAllWeightsZero(t *testing.T) {
+	cand := Candidate{BridgeConnected: true, LastSeen: now}
+	if got, want := (Config{}).Score(cand, now), Default.Score(cand, now); got.Total != want.Total {
+		t.Errorf("Total = %v, want the default %v", got.Total, want.Total)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Config
+	}{
+		{"defaults", nil, Default},
+		{
+			"overrides",
+			map[string]string{"REACH_WEIGHT_RECENCY": "1.5", "REACH_WEIGHT_TOPICS": "0", "REACH_HALF_LIFE_DAYS": "30"},
+			Config{Recency: 1.5, Bridge: 3, Seniority: 1, Topics: 0, Degree: 2, HalfLife: 30 * 24 * time.Hour},
+		},
+		{
+			"invalid values keep the defaults",
+			map[string]string{"REACH_WEIGHT_BRIDGE": "-1", "REACH_WEIGHT_DEGREE": "NaN", "REACH_WEIGHT_SENIORITY": "high", "REACH_HALF_LIFE_DAYS": "0"},
+			Default,
+		},
+		{
+			"all weights zero restore the defaults",
+			map[string]string{"REACH_WEIGHT_RECENCY": "0", "REACH_WEIGHT_BRIDGE": "0", "REACH_WEIGHT_SENIORITY": "0", "REACH_WEIGHT_TOPICS": "0", "REACH_WEIGHT_DEGREE": "0"},
+			Default,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"REACH_WEIGHT_RECENCY", "REACH_WEIGHT_BRIDGE", "REACH_WEIGHT_SENIORITY", "REACH_WEIGHT_TOPICS", "REACH_WEIGHT_DEGREE", "REACH_HALF_LIFE_DAYS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			t.Cleanup(func() { Current = Default })
+
+			Load()
+			if Current != tt.want {
+				t.Errorf("Current = %+v, want %+v", Current, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 
+	migrations "graph-migrations"
+
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
@@ -28,3 +30,16 @@ func ConnectNeo4j() {
 	Neo4jDriver = driver
 	log.Println("Connected to Neo4j")
 }
+
+// RunMigrations applies pending graph migrations (constraints, indexes, backfills)
+// unless NEO4J_MIGRATE_ON_START=false, e.g. when they are run with graph-migrations/cmd/migrate.
+func RunMigrations() {
+	if os.Getenv("NEO4J_MIGRATE_ON_START") == "false" {
+		return
+	}
+	ran, err := migrations.Run(context.Background(), Neo4jDriver, "event-service")
+	if err != nil {
+		log.Fatal("Failed to apply Neo4j migrations. \n", err)
+	}
+	log.Printf("Neo4j schema up to date (%d migrations applied)\n", len(ran))
+}
@@ -8,6 +8,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/neo4j/neo4j-go-driver/v5 v5.28.4
 	github.com/redis/go-redis/v9 v9.18.0
+	graph-migrations v0.0.0
 )
 
 require (
@@ -26,3 +27,5 @@ require (
 	go.uber.org/atomic v1.11.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 )
+
+replace graph-migrations => ../graph-migrations
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"event-service/database"
+	"event-service/middlewares"
+	"event-service/privacy"
+	"event-service/provenance"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -40,7 +42,7 @@ func ImportConnections(c *fiber.Ctx) error {
 	}
 
 	// L'UserID è iniettato dal middleware JWT — mai fidarsi del payload
-	userID := fmt.Sprintf("%.0f", c.Locals("user_id").(float64))
+	userID := middlewares.UserID(c)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -50,28 +52,39 @@ func ImportConnections(c *fiber.Ctx) error {
 	})
 	defer session.Close(ctx)
 
+	// Persone che hanno chiesto la rimozione: mai salvate, nemmeno come collegamento
+	suppressed, err := privacy.Suppressed(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load privacy settings"})
+	}
+	settings := privacy.Current
+
 	imported := 0
 	skipped := 0
 
 	for _, conn := range batch.Connections {
-		if conn.Slug == "" {
+		if conn.Slug == "" || suppressed[conn.Slug] {
 			skipped++
 			continue
 		}
 
 		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			// name/headline condivisi tra utenti: vince la fonte più affidabile (vedi package provenance)
 			_, err := tx.Run(ctx, `
 				MERGE (p:Person {slug: $slug})
-				ON CREATE SET p.name = $name, p.headline = $headline, p.created_at = datetime()
-				ON MATCH  SET p.name = $name, p.headline = $headline
+				ON CREATE SET p.created_at = datetime(), p.introduced_by = $userId
+				`+provenance.Resolve("p", "name", "name", provenance.ConnectionsImport)+`
+				`+provenance.Resolve("p", "headline", "headline", provenance.ConnectionsImport)+`
 				WITH p
 				MATCH (u:User {id: $userId})
 				MERGE (u)-[r:CONNECTED_TO]->(p)
 				ON CREATE SET r.since = $since, r.updated_at = datetime()
+				MERGE (u)-[o:OBSERVED]->(p)
+				SET o.degree = '1st', o.last_seen = toString(datetime())
 			`, map[string]any{
 				"slug":     conn.Slug,
-				"name":     conn.Name,
-				"headline": conn.Headline,
+				"name":     settings.Field(privacy.FieldName, conn.Name),
+				"headline": settings.Field(privacy.FieldHeadline, conn.Headline),
 				"userId":   userID,
 				"since":    conn.ConnectedAt,
 			})
@@ -90,3 +103,134 @@ func ImportConnections(c *fiber.Ctx) error {
 		"skipped":  skipped,
 	})
 }
+
+// MutualConnections è il payload del POST /connections/mutual: la lista dei
+// collegamenti in comune mostrata da LinkedIn sul profilo di un 2° grado.
+type MutualConnections struct {
+	Profile    Connection   `json:"profile"`     // il profilo visitato (connected_at ignorato)
+	Degree     string       `json:"degree"`      // "2nd", "3rd", ... come mostrato all'utente
+	Total      int          `json:"total"`       // totale dichiarato da LinkedIn, anche oltre la lista inviata
+	Mutual     []Connection `json:"mutual"`      // i collegamenti in comune visibili (connected_at ignorato)
+	ObservedAt time.Time    `json:"observed_at"` // default: ora
+}
+
+const maxMutualConnections = 200
+
+// ImportMutualConnections gestisce POST /connections/mutual.
+// Scrive (:Person)-[:KNOWS]->(:Person) da ciascun collegamento in comune verso
+// il profilo visitato (un solo arco per coppia: la direzione non conta, si
+// legge senza direzione). KNOWS è un fatto su due terzi, condiviso tra utenti:
+// l'arco registra chi l'ha osservato (observed_by, per la cancellazione
+// dell'account), la fonte, la prima e l'ultima osservazione. Il numero di
+// collegamenti in comune è relativo all'utente e sta sul suo OBSERVED.
+// Autenticazione: JWT + HMAC (stessi middleware di /connections/batch).
+func ImportMutualConnections(c *fiber.Ctx) error {
+	var batch MutualConnections
+	if err := c.BodyParser(&batch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if batch.Profile.Slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "profile.slug is required"})
+	}
+	if len(batch.Mutual) > maxMutualConnections {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "max 200 mutual connections per batch"})
+	}
+	if batch.ObservedAt.IsZero() || batch.ObservedAt.After(time.Now()) {
+		batch.ObservedAt = time.Now()
+	}
+
+	userID := middlewares.UserID(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	suppressed, err := privacy.Suppressed(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load privacy settings"})
+	}
+	if suppressed[batch.Profile.Slug] {
+		// Nessun arco verso chi ha chiesto la rimozione, ma non è un errore del plugin
+		return c.JSON(fiber.Map{"imported": 0, "skipped": len(batch.Mutual)})
+	}
+	settings := privacy.Current
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	observedAt := batch.ObservedAt.UTC().Format(time.RFC3339)
+	var degree any
+	if batch.Degree != "" {
+		degree = batch.Degree
+	}
+
+	imported, skipped := 0, 0
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		imported, skipped = 0, 0
+
+		_, err := tx.Run(ctx, `
+			MERGE (t:Person {slug: $slug})
+			ON CREATE SET t.created_at = datetime(), t.introduced_by = $userId
+			`+provenance.Resolve("t", "name", "name", provenance.ProfileVisit)+`
+			`+provenance.Resolve("t", "headline", "headline", provenance.ProfileVisit)+`
+			WITH t
+			MATCH (u:User {id: $userId})
+			MERGE (u)-[o:OBSERVED]->(t)
+			SET o.degree = coalesce($degree, o.degree), o.last_seen = $observedAt,
+			    o.mutual_count = CASE WHEN $total > 0 THEN $total ELSE o.mutual_count END
+		`, map[string]any{
+			"slug":       batch.Profile.Slug,
+			"name":       settings.Field(privacy.FieldName, batch.Profile.Name),
+			"headline":   settings.Field(privacy.FieldHeadline, batch.Profile.Headline),
+			"userId":     userID,
+			"degree":     degree,
+			"observedAt": observedAt,
+			"total":      max(batch.Total, len(batch.Mutual)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range batch.Mutual {
+			if m.Slug == "" || m.Slug == batch.Profile.Slug || suppressed[m.Slug] {
+				skipped++
+				continue
+			}
+			// I collegamenti in comune sono per definizione collegamenti dell'utente
+			_, err := tx.Run(ctx, `
+				MERGE (m:Person {slug: $mSlug})
+				ON CREATE SET m.created_at = datetime(), m.introduced_by = $userId
+				`+provenance.Resolve("m", "name", "mName", provenance.MutualConnection)+`
+				`+provenance.Resolve("m", "headline", "mHeadline", provenance.MutualConnection)+`
+				WITH m
+				MATCH (u:User {id: $userId}), (t:Person {slug: $slug})
+				MERGE (u)-[o:OBSERVED]->(m)
+				SET o.degree = '1st', o.last_seen = $observedAt
+				MERGE (m)-[k:KNOWS]-(t)
+				ON CREATE SET k.first_seen = $observedAt, k.source = $source, k.observed_by = []
+				SET k.last_seen = CASE WHEN coalesce(k.last_seen, '') < $observedAt THEN $observedAt ELSE k.last_seen END,
+				    k.observed_by = CASE WHEN $userId IN k.observed_by THEN k.observed_by ELSE k.observed_by + $userId END
+			`, map[string]any{
+				"mSlug":      m.Slug,
+				"mName":      settings.Field(privacy.FieldName, m.Name),
+				"mHeadline":  settings.Field(privacy.FieldHeadline, m.Headline),
+				"slug":       batch.Profile.Slug,
+				"userId":     userID,
+				"observedAt": observedAt,
+				"source":     provenance.MutualConnection.Name,
+			})
+			if err != nil {
+				return nil, err
+			}
+			imported++
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write mutual connections"})
+	}
+
+	return c.JSON(fiber.Map{
+		"imported": imported,
+		"skipped":  skipped,
+	})
+}
@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"event-service/database"
+	"event-service/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -23,7 +23,7 @@ type Mention struct {
 }
 
 type Event struct {
-	UserID          string        `json:"user_id"`
+	UserID          int64         `json:"user_id,string"`
 	PostUrn         string        `json:"post_urn"`
 	URL             string        `json:"url"`
 	Action          string        `json:"action"`
@@ -52,8 +52,7 @@ func ReceiveEvent(c *fiber.Ctx) error {
 	}
 
 	// Inject the authenticated User ID explicitly from Context (to avoid payload tampering)
-	userID := c.Locals("user_id")
-	event.UserID = fmt.Sprintf("%.0f", userID.(float64))
+	event.UserID = middlewares.UserID(c)
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
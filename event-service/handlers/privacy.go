@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"event-service/database"
+	"event-service/privacy"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type SuppressionRequest struct {
+	Slug string `json:"slug"`
+}
+
+// AddSuppression handles POST /privacy/suppressions.
+// Used when a non-user asks to be removed: their Person node, the snippets of
+// their comments and the text of their posts are erased, and the slug is
+// ignored by every future ingestion (events and connection imports).
+func AddSuppression(c *fiber.Ctx) error {
+	var req SuppressionRequest
+	if err := c.BodyParser(&req); err != nil || req.Slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "slug is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Suppress first: nothing flushed from now on can re-create the person
+	if err := database.RedisClient.SAdd(ctx, privacy.SuppressedKey, req.Slug).Err(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update suppression list"})
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	removed, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (n {slug: $slug})
+			WHERE n:Person OR n:Company
+			OPTIONAL MATCH (post:Post)-[:AUTHORED_BY]->(n)
+			REMOVE post.text
+			WITH DISTINCT n
+			DETACH DELETE n
+			RETURN count(n) AS removed
+		`, map[string]any{"slug": req.Slug})
+		if err != nil {
+			return int64(0), err
+		}
+		if rec.Next(ctx) {
+			v, _ := rec.Record().Get("removed")
+			return v.(int64), nil
+		}
+		return int64(0), rec.Err()
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Suppressed, but failed to erase graph data"})
+	}
+
+	return c.JSON(fiber.Map{"slug": req.Slug, "suppressed": true, "nodes_removed": removed})
+}
+
+// ListSuppressions handles GET /privacy/suppressions.
+func ListSuppressions(c *fiber.Ctx) error {
+	slugs, err := database.RedisClient.SMembers(database.Ctx, privacy.SuppressedKey).Result()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read suppression list"})
+	}
+	return c.JSON(fiber.Map{"slugs": slugs})
+}
+
+// RemoveSuppression handles DELETE /privacy/suppressions/:slug.
+// Erased data is not restored; the person is simply ingested again from now on.
+func RemoveSuppression(c *fiber.Ctx) error {
+	slug := c.Params("slug")
+	if err := database.RedisClient.SRem(database.Ctx, privacy.SuppressedKey, slug).Err(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update suppression list"})
+	}
+	return c.JSON(fiber.Map{"slug": slug, "suppressed": false})
+}
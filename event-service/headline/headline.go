@@ -0,0 +1,193 @@
+// Package headline extracts the current job title, seniority and company from
+// a LinkedIn headline with a few rules that cover the common shapes:
+//
+//	"Senior Backend Engineer at Acme | Go, Kubernetes"
+//	"CTO @ Acme"
+//	"Product Manager presso Acme S.p.A."
+//	"Founder & CEO, Acme"
+//
+// Headlines the rules cannot read (no company, taglines only, ...) are left
+// to the model-based fallback of dashboard-server's enrichment worker.
+package headline
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Seniority levels of the built-in taxonomy (taxonomy.json), the values of
+// Person.seniority and Job.seniority.
+const (
+	Intern      = "intern"
+	Junior      = "junior"
+	Mid         = "mid"
+	Senior      = "senior"
+	Lead        = "lead"
+	Executive   = "executive"
+	Unspecified = "unspecified"
+)
+
+// Parsed is what the rules could read from a headline. Company is empty when
+// none was found; Seniority is Unspecified when the title does not tell (the
+// rules never guess Mid, only the model does). Category is the taxonomy's
+// role category, "" when none matches.
+type Parsed struct {
+	Title     string
+	Seniority string
+	Company   string
+	Category  string
+}
+
+const (
+	maxTitleLen     = 120
+	maxCompanyLen   = 80
+	maxCompanyWords = 6
+)
+
+// segmentSeparators split a headline into independent statements.
+var segmentSeparators = []string{"|", "•", "·", " ‑ ", " – ", " — ", " - ", " // "}
+
+// companyMarkers introduce the employer after the title. Prepositions are
+// matched as whole words, so "at" does not match inside "data".
+// The comma form ("Founder & CEO, Acme") only counts after a senior title,
+// so that "Engineer, Go, Kubernetes" does not become a company.
+var companyMarkers = []string{" at ", " @ ", "@", " presso ", " chez ", " bei ", " en ", ", "}
+
+// pastMarkers open a statement about a previous job ("Ex Google", "Former CTO").
+var pastMarkers = []string{"ex ", "ex-", "former ", "formerly ", "previously ", "già ", "ex."}
+
+// companyStops end the company name ("Acme and Partners" is kept, "Acme, Speaker" is not).
+var companyStops = []string{",", " & ", " and ", " e ", " y ", " et ", " und ", " / ", "(", ";"}
+
+// Parse reads the first statement of the headline that names a current job.
+func Parse(headline string) Parsed {
+	headline = strings.Join(strings.Fields(headline), " ")
+	if headline == "" {
+		return Parsed{Seniority: Unspecified}
+	}
+
+	var parsed Parsed
+	for i, segment := range splitSegments(headline) {
+		if isPast(segment) {
+			continue
+		}
+		p := parseSegment(segment)
+		if p.Company != "" {
+			parsed = p
+			break
+		}
+		if i == 0 {
+			// A title alone is still worth keeping if no later segment has a company
+			parsed = p
+		}
+	}
+	if parsed.Seniority == "" {
+		parsed.Seniority = Unspecified
+	}
+	// The title tells the role best; taglines like "AI enthusiast" are a fallback
+	if parsed.Category = Current.CategoryOf(parsed.Title); parsed.Category == "" {
+		parsed.Category = Current.CategoryOf(headline)
+	}
+	return parsed
+}
+
+func splitSegments(headline string) []string {
+	segments := []string{headline}
+	for _, sep := range segmentSeparators {
+		var next []string
+		for _, s := range segments {
+			next = append(next, strings.Split(s, sep)...)
+		}
+		segments = next
+	}
+	var out []string
+	for _, s := range segments {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func isPast(segment string) bool {
+	lower := strings.ToLower(segment)
+	for _, m := range pastMarkers {
+		if strings.HasPrefix(lower, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSegment(segment string) Parsed {
+	lower := strings.ToLower(segment)
+	for _, marker := range companyMarkers {
+		i := strings.Index(lower, marker)
+		if i <= 0 {
+			continue
+		}
+		title := strings.TrimSpace(segment[:i])
+		company := cleanCompany(segment[i+len(marker):])
+		if title == "" || company == "" {
+			continue
+		}
+		// The marker is context for phrases like "stage presso"
+		level := seniority(segment[:i+len(marker)])
+		if marker == ", " && level != Executive && level != Lead {
+			continue
+		}
+		return Parsed{Title: truncate(title, maxTitleLen), Seniority: level, Company: company}
+	}
+	return Parsed{Title: truncate(segment, maxTitleLen), Seniority: seniority(segment)}
+}
+
+// cleanCompany cuts the company name at the first stop and rejects what does
+// not look like a name: it must start with an uppercase letter or a digit and
+// be short.
+func cleanCompany(s string) string {
+	for _, stop := range companyStops {
+		if i := strings.Index(s, stop); i >= 0 {
+			s = s[:i]
+		}
+	}
+	s = strings.Trim(s, " ,;:!-–—")
+	r, _ := utf8.DecodeRuneInString(s)
+	if s == "" || !(unicode.IsUpper(r) || unicode.IsDigit(r)) {
+		return ""
+	}
+	if utf8.RuneCountInString(s) > maxCompanyLen || len(strings.Fields(s)) > maxCompanyWords {
+		return ""
+	}
+	return s
+}
+
+// seniority is the level of a title in the current taxonomy.
+func seniority(title string) string {
+	return Current.SeniorityOf(title)
+}
+
+// normalize lowercases s and joins its words with single spaces, padded on
+// both sides for whole-word matching.
+func normalize(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+// Slug derives a company slug from its name the way LinkedIn company slugs
+// usually look: lowercase letters and digits joined by dashes.
+func Slug(company string) string {
+	words := strings.FieldsFunc(strings.ToLower(company), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n]))
+	}
+	return s
+}
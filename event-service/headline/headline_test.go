@@ -0,0 +1,43 @@
+package headline
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     Parsed
+	}{
+		{"Product Owner at Acme", Parsed{Title: "Product Owner", Seniority: Unspecified, Company: "Acme", Category: "product"}},
+		{"CEO @ Acme", Parsed{Title: "CEO", Seniority: Executive, Company: "Acme", Category: "executive"}},
+		{"Founder & CEO, Acme", Parsed{Title: "Founder & CEO", Seniority: Executive, Company: "Acme", Category: "founder"}},
+		{"Business Owner at Acme Bakery", Parsed{Title: "Business Owner", Seniority: Executive, Company: "Acme Bakery"}},
+		{"Founder of an early-stage startup", Parsed{Title: "Founder of an early-stage startup", Seniority: Executive, Category: "founder"}},
+		{"Senior Backend Engineer at Acme | Go, Kubernetes", Parsed{Title: "Senior Backend Engineer", Seniority: Senior, Company: "Acme", Category: "engineering"}},
+		{"Ex Google | Head of Data @ Acme", Parsed{Title: "Head of Data", Seniority: Lead, Company: "Acme"}},
+
+		// Italian
+		{"Product Manager presso Acme S.p.A.", Parsed{Title: "Product Manager", Seniority: Unspecified, Company: "Acme S.p.A.", Category: "product"}},
+		{"Responsabile Marketing presso Acme", Parsed{Title: "Responsabile Marketing", Seniority: Lead, Company: "Acme", Category: "marketing"}},
+		{"Stage presso Acme", Parsed{Title: "Stage", Seniority: Intern, Company: "Acme"}},
+		{"Stagista Data Analyst presso Acme", Parsed{Title: "Stagista Data Analyst", Seniority: Intern, Company: "Acme", Category: "data_ai"}},
+
+		// Spanish
+		{"Desarrolladora Senior en Acme", Parsed{Title: "Desarrolladora Senior", Seniority: Senior, Company: "Acme", Category: "engineering"}},
+		{"Becario de Marketing en Acme", Parsed{Title: "Becario de Marketing", Seniority: Intern, Company: "Acme", Category: "marketing"}},
+		{"Fundador y CEO en Acme", Parsed{Title: "Fundador y CEO", Seniority: Executive, Company: "Acme", Category: "founder"}},
+
+		// No company
+		{"Data Scientist", Parsed{Title: "Data Scientist", Seniority: Unspecified, Category: "data_ai"}},
+		{"Engineer, Go, Kubernetes", Parsed{Title: "Engineer, Go, Kubernetes", Seniority: Unspecified, Category: "engineering"}},
+		{"Software engineer at a fintech startup", Parsed{Title: "Software engineer at a fintech startup", Seniority: Unspecified, Category: "engineering"}},
+		{"Helping teams ship faster", Parsed{Title: "Helping teams ship faster", Seniority: Unspecified}},
+		{"  ", Parsed{Seniority: Unspecified}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.headline, func(t *testing.T) {
+			if got := Parse(tt.headline); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
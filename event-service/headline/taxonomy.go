@@ -0,0 +1,140 @@
+package headline
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Taxonomy maps the words of a job title to a seniority level and to one role
+// category. Terms are matched as whole words (after lowercasing and turning
+// punctuation into spaces, so "co-founder" is "co founder") and cover the
+// languages of the product: "ai" matches "AI Engineer" but not "Maintenance".
+// Levels and categories are tried in order and the first match wins, so a
+// title counts in exactly one bucket: put the more specific entries first.
+// Prefer phrases to words that also appear in other titles: "owner" alone
+// would make every "Product Owner" an executive.
+type Taxonomy struct {
+	Seniority  []Level    `json:"seniority"`
+	Categories []Category `json:"categories"`
+
+	// Version is derived from the content: people classified with another
+	// version are classified again by the headline worker.
+	Version string `json:"-"`
+}
+
+type Level struct {
+	Level string   `json:"level"`
+	Terms []string `json:"terms"`
+}
+
+type Category struct {
+	ID    string   `json:"id"`
+	Terms []string `json:"terms"`
+}
+
+//go:embed taxonomy.json
+var defaultTaxonomy []byte
+
+// Current is the taxonomy in use, set by LoadTaxonomy.
+var Current = mustParse(defaultTaxonomy)
+
+// LoadTaxonomy replaces the built-in taxonomy with HEADLINE_TAXONOMY_FILE, a
+// JSON file with the same shape as taxonomy.json, when set.
+func LoadTaxonomy() {
+	path := os.Getenv("HEADLINE_TAXONOMY_FILE")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read HEADLINE_TAXONOMY_FILE: %v", err)
+	}
+	t, err := parseTaxonomy(data)
+	if err != nil {
+		log.Fatalf("Invalid HEADLINE_TAXONOMY_FILE: %v", err)
+	}
+	Current = t
+	log.Printf("Headline taxonomy %s loaded from %s (%d categories)\n", t.Version, path, len(t.Categories))
+}
+
+func mustParse(data []byte) *Taxonomy {
+	t, err := parseTaxonomy(data)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parseTaxonomy(data []byte) (*Taxonomy, error) {
+	var t Taxonomy
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	for i := range t.Seniority {
+		if t.Seniority[i].Level == "" {
+			return nil, fmt.Errorf("seniority %d has no level", i)
+		}
+		t.Seniority[i].Terms = normalizeTerms(t.Seniority[i].Terms)
+	}
+	seen := map[string]bool{}
+	for i := range t.Categories {
+		id := t.Categories[i].ID
+		if id == "" || seen[id] {
+			return nil, fmt.Errorf("category %d has an empty or duplicate id %q", i, id)
+		}
+		seen[id] = true
+		t.Categories[i].Terms = normalizeTerms(t.Categories[i].Terms)
+	}
+	sum := sha256.Sum256(data)
+	t.Version = hex.EncodeToString(sum[:6])
+	return &t, nil
+}
+
+func normalizeTerms(terms []string) []string {
+	out := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if n := strings.TrimSpace(normalize(term)); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// SeniorityOf returns the level of a title, or Unspecified.
+func (t *Taxonomy) SeniorityOf(title string) string {
+	normalized := normalize(title)
+	for _, level := range t.Seniority {
+		if matchesAny(normalized, level.Terms) {
+			return level.Level
+		}
+	}
+	return Unspecified
+}
+
+// CategoryOf returns the id of the role category of a title, or "".
+func (t *Taxonomy) CategoryOf(title string) string {
+	normalized := normalize(title)
+	for _, c := range t.Categories {
+		if matchesAny(normalized, c.Terms) {
+			return c.ID
+		}
+	}
+	return ""
+}
+
+// matchesAny reports whether a normalize()d text contains one of the terms as
+// whole words.
+func matchesAny(normalized string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(normalized, " "+term+" ") {
+			return true
+		}
+	}
+	return false
+}
@@ -6,7 +6,9 @@ import (
 
 	"event-service/database"
 	"event-service/handlers"
+	"event-service/headline"
 	"event-service/middlewares"
+	"event-service/privacy"
 	"event-service/worker"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,6 +26,13 @@ func main() {
 	database.ConnectRedis()
 	database.ConnectNeo4j()
 	defer database.Neo4jDriver.Close(database.Ctx)
+	database.RunMigrations()
+
+	// Regole privacy per i dati di terzi (redazione campi, retention)
+	privacy.Load()
+
+	// Tassonomia dei ruoli per i titoli professionali (HEADLINE_TAXONOMY_FILE)
+	headline.LoadTaxonomy()
 
 	// Inizializza Server HTTP
 	app := fiber.New()
@@ -32,7 +41,7 @@ func main() {
 	// TODO: refine CORS on production
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Signature",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Signature, X-Admin-Token",
 	}))
 
 	// Protected Endpoint Eventi (Applica JWT + HMAC)
@@ -42,10 +51,26 @@ func main() {
 	// Protected Endpoint Connessioni (Stesso schema auth)
 	connections := app.Group("/connections", middlewares.JWTProtected(), middlewares.HMACProtected())
 	connections.Post("/batch", handlers.ImportConnections)
+	connections.Post("/mutual", handlers.ImportMutualConnections)
+
+	// Endpoint Operatore: richieste di rimozione da parte di non-utenti
+	admin := app.Group("/privacy", middlewares.AdminProtected())
+	admin.Get("/suppressions", handlers.ListSuppressions)
+	admin.Post("/suppressions", handlers.AddSuppression)
+	admin.Delete("/suppressions/:slug", handlers.RemoveSuppression)
 
 	// Avvia Worker Background Queue->Graph
 	go worker.StartFlush()
 
+	// Avvia Worker Cancellazione Account (dati su grafo e coda)
+	go worker.StartDeletionWorker()
+
+	// Avvia Worker Headline (ruolo, seniority e azienda -> WORKS_AT)
+	go worker.StartHeadlines()
+
+	// Avvia Worker Retention (snippet e testo dei post di terzi, campi redatti)
+	go worker.StartRetention()
+
 	// Listen su Porta Assegnata
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -53,3 +53,10 @@ func JWTProtected() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// UserID returns the authenticated user's ID, typed as it is stored on
+// (:User {id}) in Neo4j. The JWT claim decodes as a float64.
+func UserID(c *fiber.Ctx) int64 {
+	v, _ := c.Locals("user_id").(float64)
+	return int64(v)
+}
@@ -0,0 +1,97 @@
+// Package privacy holds the rules applied to data about people who are not
+// users of the product (post authors, co-commenters, mentions, connections):
+// field-level redaction at ingestion, the suppression list, and retention windows.
+package privacy
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"event-service/database"
+)
+
+// SuppressedKey is the Redis set of LinkedIn slugs that must never be stored.
+const SuppressedKey = "privacy:suppressed_slugs"
+
+// Redactable third-party fields.
+const (
+	FieldName           = "name"
+	FieldHeadline       = "headline"
+	FieldPostText       = "post_text"
+	FieldCommentSnippet = "comment_snippet"
+)
+
+type Settings struct {
+	Redacted              map[string]bool
+	SnippetMaxChars       int
+	SnippetRetentionDays  int
+	PostTextRetentionDays int
+}
+
+// Current is loaded once at startup by Load.
+var Current = Settings{
+	Redacted:              map[string]bool{},
+	SnippetMaxChars:       280,
+	SnippetRetentionDays:  90,
+	PostTextRetentionDays: 180,
+}
+
+// Load reads the privacy settings from the environment:
+//
+//	PRIVACY_REDACT_FIELDS             comma separated list (name,headline,post_text,comment_snippet)
+//	PRIVACY_SNIPPET_MAX_CHARS         truncate stored comment snippets (0 = no limit)
+//	PRIVACY_SNIPPET_RETENTION_DAYS    prune snippets older than this (0 = keep forever)
+//	PRIVACY_POST_TEXT_RETENTION_DAYS  prune post text older than this (0 = keep forever)
+//
+// Values stored before their field was redacted are removed by the retention
+// worker.
+func Load() {
+	if v := os.Getenv("PRIVACY_REDACT_FIELDS"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				Current.Redacted[f] = true
+			}
+		}
+	}
+	Current.SnippetMaxChars = envInt("PRIVACY_SNIPPET_MAX_CHARS", Current.SnippetMaxChars)
+	Current.SnippetRetentionDays = envInt("PRIVACY_SNIPPET_RETENTION_DAYS", Current.SnippetRetentionDays)
+	Current.PostTextRetentionDays = envInt("PRIVACY_POST_TEXT_RETENTION_DAYS", Current.PostTextRetentionDays)
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// Field returns the value to store for a third-party field: nil when the
+// field is redacted or empty (so Cypher SETs leave it unset), the value otherwise.
+func (s Settings) Field(field, value string) any {
+	if value == "" || s.Redacted[field] {
+		return nil
+	}
+	if field == FieldCommentSnippet && s.SnippetMaxChars > 0 {
+		if r := []rune(value); len(r) > s.SnippetMaxChars {
+			return string(r[:s.SnippetMaxChars])
+		}
+	}
+	return value
+}
+
+// Suppressed loads the whole suppression list, for batch ingestion.
+func Suppressed(ctx context.Context) (map[string]bool, error) {
+	slugs, err := database.RedisClient.SMembers(ctx, SuppressedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(slugs))
+	for _, s := range slugs {
+		set[s] = true
+	}
+	return set, nil
+}
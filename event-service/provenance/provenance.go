@@ -0,0 +1,59 @@
+// Package provenance decides which observation of a shared attribute wins.
+//
+// Person nodes are shared by every user, but each user's plugin reports its
+// own view of them. Viewer-relative data (e.g. the LinkedIn degree) lives on
+// the user-scoped (:User)-[:OBSERVED]->(:Person) relationship; shared
+// attributes (name, headline) stay on the node and record where they came from
+// in <field>_source, <field>_priority, <field>_by and <field>_at.
+//
+// Conflict resolution rules for a shared attribute:
+//  1. An empty or redacted value never overwrites anything.
+//  2. A source with higher priority always wins over a lower one.
+//  3. At equal priority, only the user who set the value may refresh it;
+//     other users may replace it once it is older than StaleAfterDays.
+//  4. A lower priority source only fills the attribute once it went stale.
+package provenance
+
+import "fmt"
+
+// Source identifies where an observation of a shared attribute comes from.
+type Source struct {
+	Name     string
+	Priority int
+}
+
+var (
+	// Structured data from the user's own connections page.
+	ConnectionsImport = Source{Name: "connections_import", Priority: 3}
+	// Author block of a post, rendered by LinkedIn with full profile data.
+	PostAuthor = Source{Name: "post_author", Priority: 2}
+	// Top card of a profile the user visited.
+	ProfileVisit = Source{Name: "profile_visit", Priority: 2}
+	// Names scraped from reactions, comment threads and mentions.
+	Interactor  = Source{Name: "interactor", Priority: 1}
+	CoCommenter = Source{Name: "co_commenter", Priority: 1}
+	Mention     = Source{Name: "mention", Priority: 1}
+	// Mutual connections list of a visited profile.
+	MutualConnection = Source{Name: "mutual_connection", Priority: 1}
+)
+
+// StaleAfterDays is when an attribute can be overwritten regardless of who set it.
+const StaleAfterDays = 30
+
+// Resolve returns a Cypher clause that sets variable.field to $param when the
+// rules above allow it, recording provenance alongside. The query must bind
+// $userId (the observing user). Only identifiers from code may be passed in.
+func Resolve(variable, field, param string, src Source) string {
+	v := variable + "." + field
+	stale := fmt.Sprintf("%s_at < datetime() - duration({days: %d})", v, StaleAfterDays)
+	cond := fmt.Sprintf(`$%[1]s IS NOT NULL AND $%[1]s <> '' AND (
+		%[2]s IS NULL
+		OR coalesce(%[2]s_priority, 0) < %[3]d
+		OR (coalesce(%[2]s_priority, 0) = %[3]d AND (%[2]s_by IS NULL OR %[2]s_by = $userId))
+		OR %[2]s_at IS NULL OR %[4]s)`, param, v, src.Priority, stale)
+
+	return fmt.Sprintf(`
+	FOREACH (_ IN CASE WHEN %[1]s THEN [1] ELSE [] END |
+	  SET %[2]s = $%[3]s, %[2]s_source = '%[4]s', %[2]s_priority = %[5]d, %[2]s_by = $userId, %[2]s_at = datetime())
+	`, cond, v, param, src.Name, src.Priority)
+}
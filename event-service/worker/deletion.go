@@ -0,0 +1,243 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"event-service/database"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Redis keys shared with auth-service, which purges Postgres/Redis and then
+// hands the graph side of an account deletion over to this worker.
+const (
+	DeletionQueueKey  = "account_deletion_queue"
+	DeletedUsersKey   = "deleted_users"
+	DeletionReportKey = "deletion_report:"
+)
+
+type DeletionJob struct {
+	UserID      int64     `json:"user_id,string"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// GraphDeletionReport is what remains of a user after the purge (all counters must be 0).
+type GraphDeletionReport struct {
+	UserNodes       int64 `json:"neo4j_user_nodes"`
+	PrivateEdges    int64 `json:"neo4j_private_edges"`
+	IntroducedNodes int64 `json:"neo4j_introduced_refs"` // nodes or KNOWS edges still referencing the user
+	OrphanNodes     int64 `json:"neo4j_orphan_nodes"`    // introduced nodes the purge should have deleted
+	QueuedEvents    int64 `json:"queued_events"`
+}
+
+func (r GraphDeletionReport) Clean() bool {
+	return r.UserNodes == 0 && r.PrivateEdges == 0 && r.IntroducedNodes == 0 && r.OrphanNodes == 0 && r.QueuedEvents == 0
+}
+
+// StartDeletionWorker blocks on the deletion queue and purges each user's graph data.
+func StartDeletionWorker() {
+	ctx := context.Background()
+	for {
+		res, err := database.RedisClient.BRPop(ctx, 0, DeletionQueueKey).Result()
+		if err != nil || len(res) < 2 {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		var job DeletionJob
+		if err := json.Unmarshal([]byte(res[1]), &job); err != nil || job.UserID == 0 {
+			log.Printf("Invalid deletion job %q: %v\n", res[1], err)
+			continue
+		}
+
+		if err := purgeUserGraph(ctx, job); err != nil {
+			log.Printf("Graph deletion failed for user %d, requeueing: %v\n", job.UserID, err)
+			database.RedisClient.LPush(ctx, DeletionQueueKey, res[1])
+			time.Sleep(30 * time.Second)
+		}
+	}
+}
+
+func purgeUserGraph(ctx context.Context, job DeletionJob) error {
+	if _, err := dropQueuedEvents(ctx, job.UserID); err != nil {
+		return fmt.Errorf("failed to drop queued events: %w", err)
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	introduced, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// The nodes the user introduced, before step 4 forgets who that was:
+		// the verification checks that none of them survived as an orphan
+		rec, err := tx.Run(ctx, `
+			MATCH (n {introduced_by: $userId})
+			RETURN collect(elementId(n)) AS ids
+		`, map[string]any{"userId": job.UserID})
+		if err != nil {
+			return nil, err
+		}
+		var ids []any
+		if rec.Next(ctx) {
+			v, _ := rec.Record().Get("ids")
+			ids, _ = v.([]any)
+		}
+		if err := rec.Err(); err != nil {
+			return nil, err
+		}
+
+		steps := []string{
+			// 1. The user's AI generations, then the User node and its private
+			//    edges (ACTION, CONNECTED_TO, OBSERVED, ...)
+			`MATCH (:User {id: $userId})-[:GENERATED]->(g:Generation) DETACH DELETE g`,
+			`MATCH (u:User {id: $userId}) DETACH DELETE u`,
+			// 2. KNOWS edges only this user observed (mutual connections), then
+			//    posts only this user ever acted on
+			`MATCH ()-[k:KNOWS]->()
+			 WHERE $userId IN k.observed_by
+			 SET k.observed_by = [id IN k.observed_by WHERE id <> $userId]
+			 WITH k
+			 WHERE size(k.observed_by) = 0
+			 DELETE k`,
+			`MATCH (p:Post {introduced_by: $userId})
+			 WHERE NOT ()-[:ACTION]->(p)
+			 DETACH DELETE p`,
+			// 3. Jobs extracted from those posts (dashboard-server enrichment), then
+			//    people, companies and topics they introduced that nothing else references anymore
+			//    (the WORKS_AT parsed from a person's headline alone does not keep them)
+			`MATCH (j:Job {introduced_by: $userId})
+			 WHERE NOT (:Post)-[:ANNOUNCED_IN]->(j)
+			 DETACH DELETE j`,
+			`MATCH (n:Person {introduced_by: $userId})
+			 WHERE NOT EXISTS { (n)-[r]-() WHERE NOT r:WORKS_AT }
+			 DETACH DELETE n`,
+			`MATCH (n {introduced_by: $userId})
+			 WHERE (n:Person OR n:Company OR n:Topic) AND NOT (n)--()
+			 DELETE n`,
+			// 4. Shared nodes stay, but must not keep a reference to the deleted account
+			`MATCH (n {introduced_by: $userId}) REMOVE n.introduced_by`,
+		}
+		for _, q := range steps {
+			if _, err := tx.Run(ctx, q, map[string]any{"userId": job.UserID}); err != nil {
+				return nil, err
+			}
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report, err := verifyUserDeleted(ctx, job.UserID, introduced.([]any))
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	status := "completed"
+	if !report.Clean() {
+		status = "incomplete"
+	}
+	reportKey := DeletionReportKey + strconv.FormatInt(job.UserID, 10)
+	database.RedisClient.HSet(ctx, reportKey, map[string]any{
+		"neo4j_user_nodes":      report.UserNodes,
+		"neo4j_private_edges":   report.PrivateEdges,
+		"neo4j_introduced_refs": report.IntroducedNodes,
+		"neo4j_orphan_nodes":    report.OrphanNodes,
+		"queued_events":         report.QueuedEvents,
+		"graph_purged_at":       time.Now().UTC().Format(time.RFC3339),
+		"status":                status,
+	})
+
+	log.Printf("Graph deletion for user %d %s: %+v\n", job.UserID, status, report)
+	return nil
+}
+
+// dropQueuedEvents removes the user's events still waiting in events_queue.
+func dropQueuedEvents(ctx context.Context, userID int64) (int64, error) {
+	raws, err := database.RedisClient.LRange(ctx, "events_queue", 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+	var removed int64
+	for _, raw := range raws {
+		var e Event
+		if json.Unmarshal([]byte(raw), &e) != nil || e.UserID != userID {
+			continue
+		}
+		n, err := database.RedisClient.LRem(ctx, "events_queue", 0, raw).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// verifyUserDeleted re-reads every store touched by the purge. introduced are
+// the element ids of the nodes the user introduced, captured before the purge:
+// those still there must be referenced by something else, with the same rules
+// as the purge steps.
+func verifyUserDeleted(ctx context.Context, userID int64, introduced []any) (GraphDeletionReport, error) {
+	var report GraphDeletionReport
+
+	raws, err := database.RedisClient.LRange(ctx, "events_queue", 0, -1).Result()
+	if err != nil {
+		return report, err
+	}
+	for _, raw := range raws {
+		var e Event
+		if json.Unmarshal([]byte(raw), &e) == nil && e.UserID == userID {
+			report.QueuedEvents++
+		}
+	}
+
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	_, err = session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			OPTIONAL MATCH (u:User {id: $userId})
+			OPTIONAL MATCH (u)-[r]-()
+			WITH count(DISTINCT u) AS users, count(r) AS edges
+			OPTIONAL MATCH (n {introduced_by: $userId})
+			WITH users, edges, count(n) AS introduced
+			OPTIONAL MATCH ()-[k:KNOWS]->()
+			WHERE $userId IN k.observed_by
+			WITH users, edges, introduced + count(k) AS introduced
+			CALL {
+			  UNWIND $introduced AS id
+			  MATCH (o)
+			  WHERE elementId(o) = id
+			    AND CASE
+			          WHEN o:Post THEN NOT ()-[:ACTION]->(o)
+			          WHEN o:Job THEN NOT (:Post)-[:ANNOUNCED_IN]->(o)
+			          WHEN o:Person THEN NOT EXISTS { (o)-[r]-() WHERE NOT r:WORKS_AT }
+			          WHEN o:Company OR o:Topic THEN NOT (o)--()
+			          ELSE false
+			        END
+			  RETURN count(o) AS orphans
+			}
+			RETURN users, edges, introduced, orphans
+		`, map[string]any{"userId": userID, "introduced": introduced})
+		if err != nil {
+			return nil, err
+		}
+		if rec.Next(ctx) {
+			r := rec.Record()
+			users, _ := r.Get("users")
+			edges, _ := r.Get("edges")
+			introduced, _ := r.Get("introduced")
+			report.UserNodes = users.(int64)
+			report.PrivateEdges = edges.(int64)
+			report.IntroducedNodes = introduced.(int64)
+			orphans, _ := r.Get("orphans")
+			report.OrphanNodes = orphans.(int64)
+		}
+		return nil, rec.Err()
+	})
+	return report, err
+}
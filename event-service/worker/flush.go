@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"event-service/database"
+	"event-service/privacy"
+	"event-service/provenance"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
@@ -25,7 +29,7 @@ type Mention struct {
 }
 
 type Event struct {
-	UserID          string        `json:"user_id"`
+	UserID          int64         `json:"user_id,string"`
 	PostUrn         string        `json:"post_urn"`
 	URL             string        `json:"url"`
 	Action          string        `json:"action"`
@@ -60,11 +64,19 @@ func flush() {
 	// Clear queue atomically
 	database.RedisClient.Del(ctx, "events_queue")
 
-	// Prepare data batch
+	// Prepare data batch, dropping events of accounts deleted in the meantime
+	deleted, _ := database.RedisClient.SMembers(ctx, DeletedUsersKey).Result()
+	isDeleted := make(map[int64]bool, len(deleted))
+	for _, uid := range deleted {
+		if id, err := strconv.ParseInt(uid, 10, 64); err == nil {
+			isDeleted[id] = true
+		}
+	}
+
 	var eventsToProcess []Event
 	for _, raw := range results {
 		var event Event
-		if err := json.Unmarshal([]byte(raw), &event); err == nil {
+		if err := json.Unmarshal([]byte(raw), &event); err == nil && !isDeleted[event.UserID] {
 			eventsToProcess = append(eventsToProcess, event)
 		}
 	}
@@ -79,44 +91,65 @@ func writeToNeo4j(events []Event) {
 	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+	// People on the suppression list are never written, and redacted fields are stored as null
+	suppressed, err := privacy.Suppressed(ctx)
+	if err != nil {
+		log.Printf("Failed to load suppression list, skipping flush: %v\n", err)
+		return
+	}
+	settings := privacy.Current
+
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		for _, e := range events {
 			query := `
-			MERGE (a:Person {slug: $authorSlug})
-			  ON CREATE SET a.name = $authorName, a.degree = $authorDegree
-			  ON MATCH SET a.name = $authorName, a.degree = coalesce($authorDegree, a.degree)
-			  
 			MERGE (p:Post {urn: $postUrn})
-			  ON CREATE SET p.text = $postText, p.url = $url
-			  
-			MERGE (p)-[:AUTHORED_BY]->(a)
-			
+			  ON CREATE SET p.text = $postText, p.url = $url, p.introduced_by = $userId, p.first_seen = $timestamp
+			  ON MATCH SET p.first_seen = coalesce(p.first_seen, $timestamp)
+
 			MERGE (u:User {id: $userId})
 			CREATE (u)-[:ACTION {type: $action, timestamp: $timestamp}]->(p)
 			`
 
 			params := map[string]any{
-				"userId":       e.UserID,
-				"postUrn":      e.PostUrn,
-				"url":          e.URL,
-				"action":       e.Action,
-				"authorName":   e.AuthorName,
-				"authorSlug":   e.AuthorSlug,
-				"authorDegree": e.AuthorDegree,
-				"postText":     e.PostText,
-				"timestamp":    e.Timestamp.Format(time.RFC3339),
+				"userId":    e.UserID,
+				"postUrn":   e.PostUrn,
+				"url":       e.URL,
+				"action":    e.Action,
+				"postText":  settings.Field(privacy.FieldPostText, e.PostText),
+				"timestamp": e.Timestamp.Format(time.RFC3339),
 			}
 
-			if e.InteractorSlug != "" {
+			if suppressed[e.AuthorSlug] {
+				// The author's own content is theirs too: keep only the user's action on the URN
+				params["postText"] = nil
+			} else {
+				// The degree is relative to the viewer, so it lives on the user's OBSERVED edge
+				query += `
+				MERGE (a:Person {slug: $authorSlug})
+				  ON CREATE SET a.introduced_by = $userId
+				` + provenance.Resolve("a", "name", "authorName", provenance.PostAuthor) + `
+				MERGE (p)-[:AUTHORED_BY]->(a)
+
+				MERGE (u)-[o:OBSERVED]->(a)
+				  SET o.degree = coalesce($authorDegree, o.degree), o.last_seen = $timestamp
+				`
+				params["authorSlug"] = e.AuthorSlug
+				params["authorName"] = settings.Field(privacy.FieldName, e.AuthorName)
+				params["authorDegree"] = nil
+				if e.AuthorDegree != "" {
+					params["authorDegree"] = e.AuthorDegree
+				}
+			}
+
+			if e.InteractorSlug != "" && !suppressed[e.InteractorSlug] {
 				query += `
 				MERGE (i:Person {slug: $interactorSlug})
-				  ON CREATE SET i.name = $interactorName
-				  ON MATCH SET i.name = $interactorName
-				  
+				  ON CREATE SET i.introduced_by = $userId
+				` + provenance.Resolve("i", "name", "interactorName", provenance.Interactor) + `
 				MERGE (i)-[:AMPLIFIED {type: $interactionType}]->(p)
 				`
 				params["interactorSlug"] = e.InteractorSlug
-				params["interactorName"] = e.InteractorName
+				params["interactorName"] = settings.Field(privacy.FieldName, e.InteractorName)
 				params["interactionType"] = e.InteractionType
 			}
 
@@ -127,23 +160,24 @@ func writeToNeo4j(events []Event) {
 			// Write co-commenters as separate Cypher statements (one per person)
 			// This creates Person nodes with :COMMENTED_ON edges for the Warm Reach Map bridge traversal
 			for _, cc := range e.CoCommenters {
-				if cc.Slug == "" {
+				if cc.Slug == "" || suppressed[cc.Slug] {
 					continue
 				}
 				ccQuery := `
 				MERGE (cc:Person {slug: $ccSlug})
-				  ON CREATE SET cc.name = $ccName
-				  ON MATCH SET cc.name = CASE WHEN $ccName <> '' THEN $ccName ELSE cc.name END
+				  ON CREATE SET cc.introduced_by = $userId
+				` + provenance.Resolve("cc", "name", "ccName", provenance.CoCommenter) + `
 				MERGE (p:Post {urn: $postUrn})
 				MERGE (cc)-[r:COMMENTED_ON]->(p)
 				  ON CREATE SET r.snippet = $ccSnippet, r.first_seen = $ccTs
 				`
 				ccParams := map[string]any{
 					"ccSlug":    cc.Slug,
-					"ccName":    cc.Name,
+					"ccName":    settings.Field(privacy.FieldName, cc.Name),
 					"postUrn":   e.PostUrn,
-					"ccSnippet": cc.CommentSnippet,
+					"ccSnippet": settings.Field(privacy.FieldCommentSnippet, cc.CommentSnippet),
 					"ccTs":      e.Timestamp.Format(time.RFC3339),
+					"userId":    e.UserID,
 				}
 				if _, err := tx.Run(ctx, ccQuery, ccParams); err != nil {
 					log.Printf("Failed to write co-commenter %s: %v\n", cc.Slug, err)
@@ -152,7 +186,7 @@ func writeToNeo4j(events []Event) {
 
 			// 3. Write Mentions
 			for _, m := range e.Mentions {
-				if m.Slug == "" {
+				if m.Slug == "" || suppressed[m.Slug] {
 					continue
 				}
 				label := "Person"
@@ -161,14 +195,16 @@ func writeToNeo4j(events []Event) {
 				}
 				mQuery := fmt.Sprintf(`
 				MERGE (m:%s {slug: $mSlug})
-				  ON CREATE SET m.name = $mName
+				  ON CREATE SET m.introduced_by = $userId
+				`, label) + provenance.Resolve("m", "name", "mName", provenance.Mention) + `
 				MERGE (p:Post {urn: $postUrn})
 				MERGE (p)-[:MENTIONS]->(m)
-				`, label)
+				`
 				mParams := map[string]any{
 					"mSlug":   m.Slug,
-					"mName":   m.Name,
+					"mName":   settings.Field(privacy.FieldName, m.Name),
 					"postUrn": e.PostUrn,
+					"userId":  e.UserID,
 				}
 				if _, err := tx.Run(ctx, mQuery, mParams); err != nil {
 					log.Printf("Failed to write mention %s: %v\n", m.Slug, err)
@@ -177,17 +213,20 @@ func writeToNeo4j(events []Event) {
 
 			// 4. Write Hashtags
 			for _, h := range e.Hashtags {
+				h = topicName(h)
 				if h == "" {
 					continue
 				}
 				hQuery := `
 				MERGE (t:Topic {name: $tName})
+				  ON CREATE SET t.introduced_by = $userId
 				MERGE (p:Post {urn: $postUrn})
 				MERGE (p)-[:HAS_TOPIC]->(t)
 				`
 				hParams := map[string]any{
 					"tName":   h,
 					"postUrn": e.PostUrn,
+					"userId":  e.UserID,
 				}
 				if _, err := tx.Run(ctx, hQuery, hParams); err != nil {
 					log.Printf("Failed to write hashtag %s: %v\n", h, err)
@@ -203,3 +242,10 @@ func writeToNeo4j(events []Event) {
 		log.Printf("Successfully flushed %d events to Neo4j\n", len(events))
 	}
 }
+
+// topicName normalizes a hashtag into a Topic name: no "#", trimmed and
+// lowercased, as dashboard-server's enrichment names the topics it infers, so
+// "#AI" and an inferred "ai" are the same Topic.
+func topicName(hashtag string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(hashtag), "#")))
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"event-service/database"
+	"event-service/headline"
+	"event-service/privacy"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Headline parsing turns Person.headline into Person.job_title,
+// Person.seniority and Person.role_category, plus
+// (:Person)-[:WORKS_AT {title}]->(:Company) when the headline names the
+// employer. It runs on every person whose headline changed since it was last
+// parsed (headline_at is set by provenance.Resolve), so imports, conflicting
+// observations and the backfill of existing people all go through the same
+// path.
+//
+// Person.headline_parser records the outcome: "rules" when a company was
+// found, "unparsed" when it was not. The model-based fallback in
+// dashboard-server's enrichment worker picks up the unparsed ones and sets
+// "llm"; its markers are cleared here so a changed headline is read again.
+//
+// Person.role_taxonomy is the version of the taxonomy the role was classified
+// with. When only the taxonomy changed (or the fallback rewrote the title),
+// the person is classified again from job_title without parsing the
+// headline, so the fallback's reading is kept.
+const headlineBatchSize = 500
+
+// StartHeadlines parses pending headlines every 30 seconds.
+func StartHeadlines() {
+	ticker := time.NewTicker(30 * time.Second)
+	for range ticker.C {
+		// A full batch means there is more: drain the backlog before waiting again
+		for parseHeadlines() == headlineBatchSize {
+		}
+	}
+}
+
+// parseHeadlines handles one batch and returns how many people it parsed.
+func parseHeadlines() int {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	taxonomy := headline.Current
+	pending, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (p:Person)
+			WHERE p.headline IS NOT NULL
+			WITH p, p.headline_parsed_at IS NULL OR p.headline_parsed_at < coalesce(p.headline_at, p.headline_parsed_at) AS changed
+			WHERE changed OR coalesce(p.role_taxonomy, '') <> $taxonomy
+			RETURN p.slug AS slug, p.headline AS headline, p.job_title AS title, p.headline_parser AS parser, changed
+			LIMIT $limit
+		`, map[string]any{"taxonomy": taxonomy.Version, "limit": headlineBatchSize})
+		if err != nil {
+			return nil, err
+		}
+		var people []pendingHeadline
+		for rec.Next(ctx) {
+			r := rec.Record()
+			var h pendingHeadline
+			for key, dst := range map[string]*string{"slug": &h.slug, "headline": &h.headline, "title": &h.title, "parser": &h.parser} {
+				v, _ := r.Get(key)
+				*dst, _ = v.(string)
+			}
+			changed, _ := r.Get("changed")
+			h.changed, _ = changed.(bool)
+			people = append(people, h)
+		}
+		return people, rec.Err()
+	})
+	if err != nil {
+		log.Printf("Headlines: failed to load pending people: %v\n", err)
+		return 0
+	}
+	people, _ := pending.([]pendingHeadline)
+	if len(people) == 0 {
+		return 0
+	}
+
+	// A company on the suppression list is never written, not even as an employer
+	suppressed, err := privacy.Suppressed(ctx)
+	if err != nil {
+		log.Printf("Headlines: failed to load suppression list, skipping: %v\n", err)
+		return 0
+	}
+
+	var parsedRows, classifiedRows []map[string]any
+	for _, h := range people {
+		if !h.changed {
+			// Same headline, new taxonomy: keep title and company, the model's included
+			category := taxonomy.CategoryOf(h.title)
+			if category == "" {
+				category = taxonomy.CategoryOf(h.headline)
+			}
+			row := map[string]any{"slug": h.slug, "category": nullIfEmpty(category), "seniority": nil}
+			if h.parser != "llm" {
+				row["seniority"] = taxonomy.SeniorityOf(h.title)
+			}
+			classifiedRows = append(classifiedRows, row)
+			continue
+		}
+
+		parsed := headline.Parse(h.headline)
+		slug := headline.Slug(parsed.Company)
+		if suppressed[slug] {
+			parsed.Company, slug = "", ""
+		}
+		parsedRows = append(parsedRows, map[string]any{
+			"slug":        h.slug,
+			"title":       nullIfEmpty(parsed.Title),
+			"seniority":   parsed.Seniority,
+			"category":    nullIfEmpty(parsed.Category),
+			"company":     nullIfEmpty(parsed.Company),
+			"companySlug": nullIfEmpty(slug),
+		})
+	}
+
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (p:Person {slug: row.slug})
+			SET p.job_title = row.title, p.seniority = row.seniority, p.headline_parsed_at = datetime(),
+			    p.headline_parser = CASE WHEN row.companySlug IS NULL THEN 'unparsed' ELSE 'rules' END,
+			    p.role_category = row.category, p.role_taxonomy = $taxonomy
+			REMOVE p.headline_llm_version, p.headline_llm_failed
+			WITH p, row
+			CALL {
+			  WITH p, row
+			  MATCH (p)-[old:WORKS_AT]->(c:Company)
+			  WHERE row.companySlug IS NULL OR c.slug <> row.companySlug
+			  DELETE old
+			}
+			WITH p, row
+			WHERE row.companySlug IS NOT NULL
+			MERGE (c:Company {slug: row.companySlug})
+			  ON CREATE SET c.name = row.company, c.inferred = true, c.introduced_by = p.introduced_by
+			MERGE (p)-[w:WORKS_AT]->(c)
+			SET w.title = row.title, w.source = 'rules', w.updated_at = datetime()
+		`, map[string]any{"rows": parsedRows, "taxonomy": taxonomy.Version})
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Run(ctx, `
+			UNWIND $rows AS row
+			MATCH (p:Person {slug: row.slug})
+			SET p.role_category = row.category, p.role_taxonomy = $taxonomy,
+			    p.seniority = coalesce(row.seniority, p.seniority)
+		`, map[string]any{"rows": classifiedRows, "taxonomy": taxonomy.Version})
+		return nil, err
+	})
+	if err != nil {
+		log.Printf("Headlines: failed to write %d people: %v\n", len(people), err)
+		return 0
+	}
+	return len(people)
+}
+
+type pendingHeadline struct {
+	slug     string
+	headline string
+	title    string
+	parser   string
+	changed  bool // the headline itself changed, not only the taxonomy
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
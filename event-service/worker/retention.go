@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"event-service/database"
+	"event-service/privacy"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// StartRetention prunes third-party content (comment snippets, post text)
+// once it is older than the configured retention windows, and the fields
+// PRIVACY_REDACT_FIELDS redacts that were stored before they were redacted.
+func StartRetention() {
+	pruneRedactedFields()
+	ticker := time.NewTicker(6 * time.Hour)
+	for range ticker.C {
+		pruneExpiredContent()
+		pruneRedactedFields()
+	}
+}
+
+type pruneStep struct {
+	name  string
+	query string // returns the pruned items as "pruned"
+}
+
+func pruneExpiredContent() {
+	settings := privacy.Current
+	steps := []struct {
+		pruneStep
+		days int
+	}{
+		{pruneStep{"comment snippets", `
+			MATCH (:Person)-[r:COMMENTED_ON]->(:Post)
+			WHERE r.snippet IS NOT NULL AND datetime(r.first_seen) < datetime() - duration({days: $days})
+			REMOVE r.snippet
+			RETURN count(r) AS pruned
+		`}, settings.SnippetRetentionDays},
+		{pruneStep{"post text", `
+			MATCH (p:Post)
+			WHERE p.text IS NOT NULL AND p.first_seen IS NOT NULL
+			  AND datetime(p.first_seen) < datetime() - duration({days: $days})
+			REMOVE p.text
+			RETURN count(p) AS pruned
+		`}, settings.PostTextRetentionDays},
+	}
+
+	for _, step := range steps {
+		if step.days == 0 {
+			continue
+		}
+		if pruned := prune(step.pruneStep, map[string]any{"days": step.days}); pruned > 0 {
+			log.Printf("Retention: pruned %s from %d items older than %d days\n", step.name, pruned, step.days)
+		}
+	}
+}
+
+// redactedFieldSteps remove a redacted field, with its provenance, from what
+// was stored before the field was redacted. Once done they find nothing.
+var redactedFieldSteps = map[string]pruneStep{
+	privacy.FieldName: {"names", `
+		MATCH (p:Person) WHERE p.name IS NOT NULL
+		REMOVE p.name, p.name_source, p.name_priority, p.name_by, p.name_at
+		RETURN count(p) AS pruned
+	`},
+	// Everything parsed from the headline goes with it: the job title, the
+	// role, the WORKS_AT edges (all parsed by the rules or the model, with the
+	// title) and the users' custom categories of their connections.
+	privacy.FieldHeadline: {"headlines", `
+		MATCH (p:Person)
+		WHERE p.headline IS NOT NULL OR p.job_title IS NOT NULL OR p.seniority IS NOT NULL
+		   OR p.role_category IS NOT NULL OR EXISTS { (p)-[:WORKS_AT]->() }
+		CALL {
+		  WITH p
+		  OPTIONAL MATCH (p)-[w:WORKS_AT]->()
+		  DELETE w
+		}
+		CALL {
+		  WITH p
+		  OPTIONAL MATCH (:User)-[r:CONNECTED_TO]->(p)
+		  WHERE r.custom_category_version IS NOT NULL
+		  REMOVE r.custom_category, r.custom_category_version, r.custom_category_at
+		}
+		REMOVE p.headline, p.headline_source, p.headline_priority, p.headline_by, p.headline_at,
+		       p.job_title, p.seniority, p.role_category, p.role_taxonomy,
+		       p.headline_parsed_at, p.headline_parser, p.headline_llm_version, p.headline_llm_failed
+		RETURN count(p) AS pruned
+	`},
+	privacy.FieldPostText: {"post text", `
+		MATCH (p:Post) WHERE p.text IS NOT NULL
+		REMOVE p.text
+		RETURN count(p) AS pruned
+	`},
+	privacy.FieldCommentSnippet: {"comment snippets", `
+		MATCH (:Person)-[r:COMMENTED_ON]->(:Post) WHERE r.snippet IS NOT NULL
+		REMOVE r.snippet
+		RETURN count(r) AS pruned
+	`},
+}
+
+func pruneRedactedFields() {
+	for field, step := range redactedFieldSteps {
+		if !privacy.Current.Redacted[field] {
+			continue
+		}
+		if pruned := prune(step, nil); pruned > 0 {
+			log.Printf("Retention: removed redacted %s from %d items\n", step.name, pruned)
+		}
+	}
+}
+
+// prune runs a step in its own transaction and returns how many items it
+// pruned, logging failures.
+func prune(step pruneStep, params map[string]any) int64 {
+	ctx := context.Background()
+	session := database.Neo4jDriver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	pruned, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, step.query, params)
+		if err != nil {
+			return int64(0), err
+		}
+		if rec.Next(ctx) {
+			v, _ := rec.Record().Get("pruned")
+			return v.(int64), nil
+		}
+		return int64(0), rec.Err()
+	})
+	if err != nil {
+		log.Printf("Retention: failed to prune %s: %v\n", step.name, err)
+		return 0
+	}
+	return pruned.(int64)
+}
@@ -0,0 +1,63 @@
+// Command migrate applies or lists the Neo4j graph migrations.
+//
+//	go run ./cmd/migrate          # apply pending migrations
+//	go run ./cmd/migrate -status  # list migrations and their state
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	migrations "graph-migrations"
+
+	"github.com/joho/godotenv"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func main() {
+	status := flag.Bool("status", false, "list migrations instead of applying them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found; using environment variables instead")
+	}
+
+	uri := os.Getenv("NEO4J_URI")
+	if uri == "" {
+		uri = "bolt://localhost:7687"
+	}
+
+	ctx := context.Background()
+	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(os.Getenv("NEO4J_USER"), os.Getenv("NEO4J_PASSWORD"), ""))
+	if err != nil {
+		log.Fatalf("Failed to create driver: %v", err)
+	}
+	defer driver.Close(ctx)
+
+	if *status {
+		list, err := migrations.List(ctx, driver)
+		if err != nil {
+			log.Fatalf("Failed to read migrations: %v", err)
+		}
+		for _, m := range list {
+			state := "pending"
+			if m.Applied {
+				state = "applied " + m.AppliedAt
+			}
+			if m.Modified {
+				state += " (MODIFIED)"
+			}
+			fmt.Printf("%04d_%-30s %s\n", m.Version, m.Name, state)
+		}
+		return
+	}
+
+	ran, err := migrations.Run(ctx, driver, "cli")
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	fmt.Printf("Applied %d migration(s)\n", len(ran))
+}
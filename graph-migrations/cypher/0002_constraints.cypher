@@ -0,0 +1,7 @@
+// Uniqueness constraints for every key used in MERGE by event-service and
+// dashboard-server. They also back an index, so MERGE no longer label-scans.
+CREATE CONSTRAINT person_slug_unique IF NOT EXISTS FOR (p:Person) REQUIRE p.slug IS UNIQUE;
+CREATE CONSTRAINT company_slug_unique IF NOT EXISTS FOR (c:Company) REQUIRE c.slug IS UNIQUE;
+CREATE CONSTRAINT post_urn_unique IF NOT EXISTS FOR (p:Post) REQUIRE p.urn IS UNIQUE;
+CREATE CONSTRAINT user_id_unique IF NOT EXISTS FOR (u:User) REQUIRE u.id IS UNIQUE;
+CREATE CONSTRAINT topic_name_unique IF NOT EXISTS FOR (t:Topic) REQUIRE t.name IS UNIQUE;
@@ -0,0 +1,8 @@
+// Lookup indexes for the dashboard queries, the retention worker and the
+// account deletion worker.
+CREATE INDEX action_type IF NOT EXISTS FOR ()-[a:ACTION]-() ON (a.type);
+CREATE INDEX action_timestamp IF NOT EXISTS FOR ()-[a:ACTION]-() ON (a.timestamp);
+CREATE INDEX commented_on_first_seen IF NOT EXISTS FOR ()-[r:COMMENTED_ON]-() ON (r.first_seen);
+CREATE INDEX post_first_seen IF NOT EXISTS FOR (p:Post) ON (p.first_seen);
+CREATE INDEX post_introduced_by IF NOT EXISTS FOR (p:Post) ON (p.introduced_by);
+CREATE INDEX person_introduced_by IF NOT EXISTS FOR (p:Person) ON (p.introduced_by);
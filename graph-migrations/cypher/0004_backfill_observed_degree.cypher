@@ -0,0 +1,17 @@
+// Person.degree was overwritten by whichever user saw the author last.
+// Keep it only as the introducing user's own observation, then drop it.
+MATCH (p:Person)
+WHERE p.degree IS NOT NULL AND p.introduced_by IS NOT NULL
+MATCH (u:User {id: p.introduced_by})
+MERGE (u)-[o:OBSERVED]->(p)
+SET o.degree = coalesce(o.degree, p.degree);
+
+MATCH (p:Person)
+WHERE p.degree IS NOT NULL
+REMOVE p.degree;
+
+// Posts ingested before the retention worker existed have no first_seen.
+MATCH (:User)-[a:ACTION]->(p:Post)
+WHERE p.first_seen IS NULL
+WITH p, min(a.timestamp) AS first
+SET p.first_seen = first;
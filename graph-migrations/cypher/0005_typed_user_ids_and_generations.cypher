@@ -0,0 +1,33 @@
+// User IDs were written as strings ("42") by event-service and
+// dashboard-server; they are integers from now on, like the Postgres key.
+MATCH (u:User)
+WHERE u.id = toString(u.id) AND toInteger(u.id) IS NOT NULL
+SET u.id = toInteger(u.id);
+
+// Same for every property holding a user ID.
+MATCH (n)
+WHERE n.introduced_by = toString(n.introduced_by) AND toInteger(n.introduced_by) IS NOT NULL
+SET n.introduced_by = toInteger(n.introduced_by);
+
+MATCH (n)
+WHERE n.name_by = toString(n.name_by) AND toInteger(n.name_by) IS NOT NULL
+SET n.name_by = toInteger(n.name_by);
+
+MATCH (n)
+WHERE n.headline_by = toString(n.headline_by) AND toInteger(n.headline_by) IS NOT NULL
+SET n.headline_by = toInteger(n.headline_by);
+
+// dashboard-server used to log each AI generation as an ACTION towards a fake
+// (:Post {urn: 'ai_gen_internal'}). Turn them into Generation nodes; the real
+// post is unknown for these, so they have no FOR_POST edge.
+MATCH (u:User)-[a:ACTION]->(p:Post {urn: 'ai_gen_internal'})
+CREATE (u)-[:GENERATED]->(:Generation {
+  id: randomUUID(),
+  type: CASE a.type WHEN 'comment_generated' THEN 'comment' ELSE a.type END,
+  created_at: datetime(a.timestamp),
+  backfilled: true
+})
+DELETE a;
+
+MATCH (p:Post {urn: 'ai_gen_internal'})
+DETACH DELETE p;
@@ -0,0 +1,2 @@
+CREATE CONSTRAINT generation_id_unique IF NOT EXISTS FOR (g:Generation) REQUIRE g.id IS UNIQUE;
+CREATE INDEX generation_created_at IF NOT EXISTS FOR (g:Generation) ON (g.created_at);
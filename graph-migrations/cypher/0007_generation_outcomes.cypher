@@ -0,0 +1,3 @@
+// Lookups for the comment feedback analytics (outcome per prompt version).
+CREATE INDEX generation_prompt_version IF NOT EXISTS FOR (g:Generation) ON (g.prompt_version);
+CREATE INDEX generation_outcome IF NOT EXISTS FOR (g:Generation) ON (g.outcome);
@@ -0,0 +1,4 @@
+// Comment generations are grouped in metering sessions: the variants of one
+// request and its regenerations share a session_id. Generations recorded
+// before this have none and count as their own session.
+CREATE INDEX generation_session_id IF NOT EXISTS FOR (g:Generation) ON (g.session_id);
@@ -0,0 +1,5 @@
+// Post enrichment (dashboard-server/enrichment): pending posts are looked up
+// by enrichment version, and the insights filter on intent and sentiment.
+CREATE INDEX post_enrichment_version IF NOT EXISTS FOR (p:Post) ON (p.enrichment_version);
+CREATE INDEX post_intent IF NOT EXISTS FOR (p:Post) ON (p.intent);
+CREATE INDEX post_sentiment IF NOT EXISTS FOR (p:Post) ON (p.sentiment);
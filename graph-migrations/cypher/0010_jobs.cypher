@@ -0,0 +1,5 @@
+// Job openings extracted from hiring posts:
+// (:Company)-[:HIRING]->(:Job)<-[:ANNOUNCED_IN]-(:Post). Job.id is "<post urn>#<n>".
+CREATE CONSTRAINT job_id_unique IF NOT EXISTS FOR (j:Job) REQUIRE j.id IS UNIQUE;
+CREATE INDEX job_announced_at IF NOT EXISTS FOR (j:Job) ON (j.announced_at);
+CREATE INDEX post_jobs_version IF NOT EXISTS FOR (p:Post) ON (p.jobs_version);
@@ -0,0 +1,6 @@
+// Headline parsing (event-service worker/headlines.go, with the enrichment
+// fallback in dashboard-server): pending people are looked up by parse time
+// and outcome, the insights group connections by seniority.
+CREATE INDEX person_headline_parsed_at IF NOT EXISTS FOR (p:Person) ON (p.headline_parsed_at);
+CREATE INDEX person_headline_parser IF NOT EXISTS FOR (p:Person) ON (p.headline_parser);
+CREATE INDEX person_seniority IF NOT EXISTS FOR (p:Person) ON (p.seniority);
@@ -0,0 +1,5 @@
+// Role taxonomy (event-service headline/taxonomy.json): people classified with
+// another taxonomy version are found by role_taxonomy, the insights group
+// connections by role_category.
+CREATE INDEX person_role_taxonomy IF NOT EXISTS FOR (p:Person) ON (p.role_taxonomy);
+CREATE INDEX person_role_category IF NOT EXISTS FOR (p:Person) ON (p.role_category);
@@ -0,0 +1,3 @@
+// Mutual connections (event-service POST /connections/mutual):
+// (:Person)-[:KNOWS {first_seen, last_seen, source, observed_by}]-(:Person).
+CREATE INDEX knows_last_seen IF NOT EXISTS FOR ()-[k:KNOWS]-() ON (k.last_seen);
@@ -0,0 +1,27 @@
+// Topic names are normalized by both writers (no "#", trimmed, lowercased):
+// hashtags used to be stored verbatim, so "#AI", "AI" and the inferred "ai"
+// were three Topics. Their HAS_TOPIC edges move to the normalized Topic, which
+// is created if needed, then the old Topics are deleted. An edge is inferred
+// only if every merged edge was.
+//
+// Topics with nothing left after normalizing ("#", blank) are not merged
+// anywhere: the last statement deletes them with their edges on purpose, as
+// both writers now skip such names.
+MATCH (t:Topic)
+WITH t, toLower(trim(CASE WHEN trim(t.name) STARTS WITH '#' THEN substring(trim(t.name), 1) ELSE t.name END)) AS name
+WHERE name <> t.name AND name <> ''
+MERGE (n:Topic {name: name})
+  ON CREATE SET n.introduced_by = t.introduced_by;
+
+MATCH (p:Post)-[r:HAS_TOPIC]->(t:Topic)
+WITH p, r, t, toLower(trim(CASE WHEN trim(t.name) STARTS WITH '#' THEN substring(trim(t.name), 1) ELSE t.name END)) AS name
+WHERE name <> t.name AND name <> ''
+MATCH (n:Topic {name: name})
+MERGE (p)-[moved:HAS_TOPIC]->(n)
+  ON CREATE SET moved += properties(r)
+  ON MATCH SET moved.inferred = CASE WHEN moved.inferred AND r.inferred THEN true END
+DELETE r;
+
+MATCH (t:Topic)
+WHERE toLower(trim(CASE WHEN trim(t.name) STARTS WITH '#' THEN substring(trim(t.name), 1) ELSE t.name END)) <> t.name
+DETACH DELETE t;
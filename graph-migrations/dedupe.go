@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// mergeKeys are the node keys used by MERGE across the services. Concurrent
+// MERGEs without a uniqueness constraint may have created duplicates, which
+// must be collapsed before the constraints of migration 0002 can be created.
+var mergeKeys = []struct{ label, key string }{
+	{"Person", "slug"},
+	{"Company", "slug"},
+	{"Post", "urn"},
+	{"User", "id"},
+	{"Topic", "name"},
+}
+
+func dedupeMergeKeys(ctx context.Context, driver neo4j.DriverWithContext) error {
+	for _, k := range mergeKeys {
+		if err := dedupeNodes(ctx, driver, k.label, k.key); err != nil {
+			return fmt.Errorf("dedupe %s.%s: %w", k.label, k.key, err)
+		}
+	}
+	return nil
+}
+
+// dedupeNodes keeps the first node of each duplicate group, moves every
+// relationship of the others onto it, fills in missing properties and
+// deletes the duplicates. Relationship types are rebuilt from type(r), so it
+// works for every edge type without APOC.
+func dedupeNodes(ctx context.Context, driver neo4j.DriverWithContext, label, key string) error {
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	groups, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, fmt.Sprintf(`
+			MATCH (n:%s) WHERE n.%s IS NOT NULL
+			WITH n.%s AS k, collect(elementId(n)) AS ids
+			WHERE size(ids) > 1
+			RETURN ids
+		`, quote(label), quote(key), quote(key)), nil)
+		if err != nil {
+			return nil, err
+		}
+		var groups [][]string
+		for rec.Next(ctx) {
+			raw, _ := rec.Record().Get("ids")
+			var ids []string
+			for _, id := range raw.([]any) {
+				ids = append(ids, id.(string))
+			}
+			groups = append(groups, ids)
+		}
+		return groups, rec.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ids := range groups.([][]string) {
+		keep := ids[0]
+		for _, dup := range ids[1:] {
+			if _, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+				return nil, mergeInto(ctx, tx, keep, dup)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func mergeInto(ctx context.Context, tx neo4j.ManagedTransaction, keep, dup string) error {
+	rec, err := tx.Run(ctx, `
+		MATCH (d) WHERE elementId(d) = $dup
+		MATCH (d)-[r]-(o)
+		WHERE elementId(o) <> $keep
+		RETURN type(r) AS type, startNode(r) = d AS outgoing, elementId(o) AS other, properties(r) AS props
+	`, map[string]any{"dup": dup, "keep": keep})
+	if err != nil {
+		return err
+	}
+	records, err := rec.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		relType, _ := r.Get("type")
+		outgoing, _ := r.Get("outgoing")
+		other, _ := r.Get("other")
+		props, _ := r.Get("props")
+
+		pattern := "(k)-[r:%s]->(o)"
+		if !outgoing.(bool) {
+			pattern = "(k)<-[r:%s]-(o)"
+		}
+		query := fmt.Sprintf(`
+			MATCH (k) WHERE elementId(k) = $keep
+			MATCH (o) WHERE elementId(o) = $other
+			CREATE `+pattern+`
+			SET r = $props
+		`, quote(relType.(string)))
+		if _, err := tx.Run(ctx, query, map[string]any{"keep": keep, "other": other, "props": props}); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Run(ctx, `
+		MATCH (k) WHERE elementId(k) = $keep
+		MATCH (d) WHERE elementId(d) = $dup
+		WITH k, d, properties(k) AS original
+		SET k += properties(d)
+		SET k += original
+		DETACH DELETE d
+	`, map[string]any{"dup": dup, "keep": keep})
+	return err
+}
+
+// quote escapes an identifier (label, property or relationship type) for Cypher.
+func quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
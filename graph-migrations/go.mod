@@ -0,0 +1,8 @@
+module graph-migrations
+
+go 1.24.3
+
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/neo4j/neo4j-go-driver/v5 v5.28.4
+)
@@ -0,0 +1,223 @@
+// Package migrations is the versioned schema/data migration runner for the
+// shared Neo4j graph. It is used by event-service and dashboard-server at
+// startup and by cmd/migrate from the command line.
+//
+// Applied migrations are tracked as (:SchemaMigration {version, name,
+// checksum, applied_at, applied_by}) nodes. Every migration must be
+// idempotent (IF NOT EXISTS, MERGE, WHERE ... IS NULL), so two services
+// starting at the same time can both run it without harm.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+//go:embed cypher/*.cypher
+var cypherFiles embed.FS
+
+// Migration is either a list of Cypher statements or a Go function, for data
+// repairs that cannot be expressed in plain Cypher without APOC.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+	Func       func(ctx context.Context, driver neo4j.DriverWithContext) error
+
+	// Revision stands for the code of Func in the checksum, which cannot
+	// hash it: bump it (from 0, the first revision) on any change to Func,
+	// or to what it calls, after it shipped.
+	Revision int
+}
+
+// Checksum detects migrations edited after being applied. Statements are
+// hashed as they are; a Func only through its Revision.
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.Name))
+	for _, s := range m.Statements {
+		h.Write([]byte(s))
+	}
+	if m.Func != nil && m.Revision > 0 {
+		fmt.Fprintf(h, "revision %d", m.Revision)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Status of a known migration against the database.
+type Status struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+	Modified  bool   `json:"modified"`
+}
+
+// cypher loads an embedded file as a list of statements separated by ";".
+// Lines starting with "//" are comments.
+func cypher(file string) []string {
+	raw, err := cypherFiles.ReadFile("cypher/" + file)
+	if err != nil {
+		panic(fmt.Sprintf("migrations: missing %s: %v", file, err))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines = append(lines, line)
+		}
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// Run applies every pending migration in version order. appliedBy identifies
+// the caller in the history (e.g. "event-service").
+func Run(ctx context.Context, driver neo4j.DriverWithContext, appliedBy string) ([]Migration, error) {
+	applied, err := appliedMigrations(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range sorted() {
+		if h, ok := applied[m.Version]; ok {
+			if h.checksum != m.Checksum() {
+				log.Printf("migrations: %04d_%s was modified after being applied\n", m.Version, m.Name)
+			}
+			continue
+		}
+
+		start := time.Now()
+		if err := apply(ctx, driver, m); err != nil {
+			return ran, fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if err := record(ctx, driver, m, appliedBy); err != nil {
+			return ran, fmt.Errorf("migration %04d_%s applied but not recorded: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migrations: applied %04d_%s in %s\n", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// List reports every known migration and whether it has been applied.
+func List(ctx context.Context, driver neo4j.DriverWithContext) ([]Status, error) {
+	applied, err := appliedMigrations(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range sorted() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if h, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = h.appliedAt
+			s.Modified = h.checksum != m.Checksum()
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+func sorted() []Migration {
+	all := append([]Migration(nil), All...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func appliedMigrations(ctx context.Context, driver neo4j.DriverWithContext) (map[int]appliedMigration, error) {
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		rec, err := tx.Run(ctx, `
+			MATCH (m:SchemaMigration)
+			RETURN m.version AS version, m.checksum AS checksum, toString(m.applied_at) AS applied_at
+		`, nil)
+		if err != nil {
+			return nil, err
+		}
+		applied := map[int]appliedMigration{}
+		for rec.Next(ctx) {
+			r := rec.Record()
+			version, _ := r.Get("version")
+			checksum, _ := r.Get("checksum")
+			appliedAt, _ := r.Get("applied_at")
+			applied[int(version.(int64))] = appliedMigration{
+				checksum:  fmt.Sprint(checksum),
+				appliedAt: fmt.Sprint(appliedAt),
+			}
+		}
+		return applied, rec.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	return result.(map[int]appliedMigration), nil
+}
+
+// apply runs each statement in its own transaction: Neo4j does not allow
+// schema and data changes in the same transaction.
+func apply(ctx context.Context, driver neo4j.DriverWithContext, m Migration) error {
+	if m.Func != nil {
+		return m.Func(ctx, driver)
+	}
+
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	for _, stmt := range m.Statements {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			res, err := tx.Run(ctx, stmt, nil)
+			if err != nil {
+				return nil, err
+			}
+			return res.Consume(ctx)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func record(ctx context.Context, driver neo4j.DriverWithContext, m Migration, appliedBy string) error {
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			MERGE (m:SchemaMigration {version: $version})
+			ON CREATE SET m.name = $name, m.checksum = $checksum, m.applied_at = datetime(), m.applied_by = $appliedBy
+		`, map[string]any{
+			"version":   m.Version,
+			"name":      m.Name,
+			"checksum":  m.Checksum(),
+			"appliedBy": appliedBy,
+		})
+		return nil, err
+	})
+	return err
+}
@@ -0,0 +1,20 @@
+package migrations
+
+// All is the ordered migration history. Never edit or renumber an entry that
+// has shipped: add a new version instead.
+var All = []Migration{
+	{Version: 1, Name: "dedupe_merge_keys", Func: dedupeMergeKeys},
+	{Version: 2, Name: "constraints", Statements: cypher("0002_constraints.cypher")},
+	{Version: 3, Name: "indexes", Statements: cypher("0003_indexes.cypher")},
+	{Version: 4, Name: "backfill_observed_degree", Statements: cypher("0004_backfill_observed_degree.cypher")},
+	{Version: 5, Name: "typed_user_ids_and_generations", Statements: cypher("0005_typed_user_ids_and_generations.cypher")},
+	{Version: 6, Name: "generation_constraints", Statements: cypher("0006_generation_constraints.cypher")},
+	{Version: 7, Name: "generation_outcomes", Statements: cypher("0007_generation_outcomes.cypher")},
+	{Version: 8, Name: "generation_sessions", Statements: cypher("0008_generation_sessions.cypher")},
+	{Version: 9, Name: "post_enrichment", Statements: cypher("0009_post_enrichment.cypher")},
+	{Version: 10, Name: "jobs", Statements: cypher("0010_jobs.cypher")},
+	{Version: 11, Name: "headlines", Statements: cypher("0011_headlines.cypher")},
+	{Version: 12, Name: "role_taxonomy", Statements: cypher("0012_role_taxonomy.cypher")},
+	{Version: 13, Name: "knows", Statements: cypher("0013_knows.cypher")},
+	{Version: 14, Name: "normalize_topics", Statements: cypher("0014_normalize_topics.cypher")},
+}
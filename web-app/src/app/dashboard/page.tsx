@@ -121,7 +121,7 @@ export default function DashboardPage() {
                 Promise.all([
                     apiFetch<Stats>("/api/stats").then(setStats).catch(() => { }),
                     apiFetch<ActivityItem[]>("/api/activity?limit=5").then(setActivity).catch(() => { }),
-                    apiFetch<BridgeTarget[]>("/api/bridge-targets").then(setBridges).catch(() => { }),
+                    apiFetch<{ targets: BridgeTarget[] }>("/api/bridge-targets").then(p => setBridges(p.targets ?? [])).catch(() => { }),
                     apiFetch<Usage>("/api/user/usage").then(setUsage).catch(() => { }),
                 ]).finally(() => setLoading(false));
             })
@@ -36,7 +36,7 @@ export default function ReachPage() {
                 if (!u) return;
                 setUser(u);
                 fetch(`${DASH_URL}/api/bridge-targets`, { credentials: "include" })
-                    .then(r => r.json()).then(setBridges).finally(() => setLoading(false));
+                    .then(r => r.json()).then((p: { targets: BridgeTarget[] }) => setBridges(p.targets ?? [])).finally(() => setLoading(false));
             }).catch(() => { window.location.href = AUTH_LOGIN_URL; });
     }, []);
 